@@ -6,32 +6,32 @@ generated Go code.
 The package breaks sending and responding to HTTP requests into three phases: Preparing, Sending,
 and Responding. A typical pattern is:
 
-  req, err := Prepare(&http.Request{},
-    token.WithAuthorization())
+	req, err := Prepare(&http.Request{},
+		token.WithAuthorization())
 
-  resp, err := Send(req,
-    WithLogging(logger),
-    DoErrorIfStatusCode(http.StatusInternalServerError),
-    DoCloseIfError(),
-    DoRetryForAttempts(5, time.Second))
+	resp, err := Send(req,
+		WithLogging(logger),
+		DoErrorIfStatusCode(http.StatusInternalServerError),
+		DoCloseIfError(),
+		DoRetryForAttempts(5, time.Second))
 
-  err = Respond(resp,
-    ByClosing())
+	err = Respond(resp,
+		ByClosing())
 
 Each phase relies on decorators to modify and / or manage processing. Decorators may first modify
 and then pass the data along, pass the data first and then modify the result, or wrap themselves
 around passing the data (such as a logger might do). Decorators run in the order provided. For
 example, the following:
 
-  req, err := Prepare(&http.Request{},
-    WithBaseURL("https://microsoft.com/"),
-    WithPath("a"),
-    WithPath("b"),
-    WithPath("c"))
+	req, err := Prepare(&http.Request{},
+		WithBaseURL("https://microsoft.com/"),
+		WithPath("a"),
+		WithPath("b"),
+		WithPath("c"))
 
 will set the URL to:
 
-  https://microsoft.com/a/b/c
+	https://microsoft.com/a/b/c
 
 Preparers and Responders may be shared and re-used (assuming the underlying decorators support
 sharing and re-use). Performant use is obtained by creating one or more Preparers and Responders
@@ -58,12 +58,24 @@ package autorest
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 )
 
 const (
-	headerLocation   = "Location"
-	headerRetryAfter = "Retry-After"
+	headerClientRequestID      = "x-ms-client-request-id"
+	headerContentCRC64         = "x-ms-content-crc64"
+	headerContentEncoding      = "Content-Encoding"
+	headerContentLength        = "Content-Length"
+	headerContentMD5           = "Content-MD5"
+	headerContentSHA256        = "x-ms-content-sha256"
+	headerCorrelationRequestID = "x-ms-correlation-request-id"
+	headerETag                 = "ETag"
+	headerLink                 = "Link"
+	headerLocation             = "Location"
+	headerRequestID            = "x-ms-request-id"
+	headerRetryAfter           = "Retry-After"
+	headerWWWAuthenticate      = "WWW-Authenticate"
 )
 
 // ResponseHasStatusCode returns true if the status code in the HTTP Response is in the passed set
@@ -126,6 +138,33 @@ func GetPollingDelay(resp *http.Response, defaultDelay time.Duration) time.Durat
 	return d
 }
 
+// GetRetryAfter extracts the delay requested by the Retry-After header of the passed response, which
+// may be either a number of seconds or an HTTP-date (see RFC 7231, section 7.1.3). It returns false
+// if the header is absent or malformed. Dates in the past yield a zero delay.
+func GetRetryAfter(resp *http.Response) (time.Duration, bool) {
+	retry := resp.Header.Get(headerRetryAfter)
+	if retry == "" {
+		return 0, false
+	}
+
+	if s, err := strconv.Atoi(retry); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+
+	t, err := http.ParseTime(retry)
+	if err != nil {
+		return 0, false
+	}
+	d := t.Sub(DefaultClock.Now())
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
 // GetPollingLocation retrieves the polling URL from the Location header of the passed response.
 func GetPollingLocation(resp *http.Response) string {
 	return resp.Header.Get(headerLocation)
@@ -346,3 +346,54 @@ func TestDecorateForPollingCloseBodyOnEachAttempt(t *testing.T) {
 			5, resp.Body.(*mocks.Body).CloseAttempts())
 	}
 }
+
+func TestGetRetryAfterParsesSeconds(t *testing.T) {
+	resp := mocks.NewResponse()
+	mocks.SetResponseHeader(resp, "Retry-After", "42")
+
+	d, ok := GetRetryAfter(resp)
+	if !ok || d != 42*time.Second {
+		t.Errorf("autorest: GetRetryAfter failed to parse seconds -- expected %v, received %v", 42*time.Second, d)
+	}
+}
+
+func TestGetRetryAfterParsesHTTPDate(t *testing.T) {
+	resp := mocks.NewResponse()
+	mocks.SetResponseHeader(resp, "Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+
+	d, ok := GetRetryAfter(resp)
+	if !ok || d <= 59*time.Minute || d > time.Hour {
+		t.Errorf("autorest: GetRetryAfter failed to parse an HTTP-date -- received %v", d)
+	}
+}
+
+func TestGetRetryAfterReturnsZeroForPastHTTPDate(t *testing.T) {
+	resp := mocks.NewResponse()
+	mocks.SetResponseHeader(resp, "Retry-After", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+
+	d, ok := GetRetryAfter(resp)
+	if !ok || d != 0 {
+		t.Errorf("autorest: GetRetryAfter failed to return zero for a past HTTP-date -- received %v", d)
+	}
+}
+
+func TestGetRetryAfterHandlesMissingOrMalformedHeader(t *testing.T) {
+	resp := mocks.NewResponse()
+	if _, ok := GetRetryAfter(resp); ok {
+		t.Errorf("autorest: GetRetryAfter returned a delay for a missing header")
+	}
+
+	mocks.SetResponseHeader(resp, "Retry-After", "soon")
+	if _, ok := GetRetryAfter(resp); ok {
+		t.Errorf("autorest: GetRetryAfter returned a delay for a malformed header")
+	}
+}
+
+func TestGetRetryAfterUsesDefaultClock(t *testing.T) {
+	tc := useTestClock(t)
+	resp := mocks.NewResponse()
+	mocks.SetResponseHeader(resp, "Retry-After", tc.Now().Add(90*time.Second).Format(http.TimeFormat))
+	if d, ok := GetRetryAfter(resp); !ok || d != 90*time.Second {
+		t.Errorf("autorest: GetRetryAfter returned %v -- expected 1m30s", d)
+	}
+}
@@ -0,0 +1,141 @@
+package autorest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// recordedResponse is a response, and the request that produced it, as recorded by
+// ByRecordingToFile.
+type recordedResponse struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Status     string      `json:"status"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// ByRecordingToFile returns a RespondDecorator that, before invoking the passed Responder, appends
+// to the file at path (creating it if necessary) a line of JSON recording the response status,
+// headers, and body along with the method and URL of its request. The body is restored so that
+// subsequent decorators see it unmodified. A file recorded over a sequence of requests may be
+// replayed with ByReplayingFromFile.
+func ByRecordingToFile(path string) RespondDecorator {
+	var mu sync.Mutex
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil {
+				return r.Respond(resp)
+			}
+			rr := recordedResponse{
+				Status:     resp.Status,
+				StatusCode: resp.StatusCode,
+				Header:     resp.Header,
+			}
+			if resp.Request != nil {
+				rr.Method = resp.Request.Method
+				if resp.Request.URL != nil {
+					rr.URL = resp.Request.URL.String()
+				}
+			}
+			if resp.Body != nil {
+				b, err := ioutil.ReadAll(resp.Body)
+				resp.Body = readCloser{Reader: bytes.NewReader(b), Closer: resp.Body}
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByRecordingToFile", resp.StatusCode, "Failure reading response body")
+				}
+				rr.Body = b
+			}
+			line, err := json.Marshal(rr)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByRecordingToFile", resp.StatusCode, "Failure encoding response")
+			}
+
+			mu.Lock()
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+			if err == nil {
+				_, err = f.Write(append(line, '\n'))
+				if cerr := f.Close(); err == nil {
+					err = cerr
+				}
+			}
+			mu.Unlock()
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByRecordingToFile", resp.StatusCode, "Failure recording response to %s", path)
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByReplayingFromFile returns a Responder that replays, in order, the responses recorded to the
+// file at path by ByRecordingToFile. Each call to Respond replaces the status, headers, and body of
+// the passed http.Response with the next recorded response. It returns an error if the URL of the
+// response's http.Request differs from the recorded one, if the file cannot be read, or once all
+// recorded responses have been replayed. The file is read on the first call to Respond.
+func ByReplayingFromFile(path string) Responder {
+	var (
+		mu        sync.Mutex
+		loaded    bool
+		responses []recordedResponse
+		next      int
+	)
+	return ResponderFunc(func(resp *http.Response) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !loaded {
+			f, err := os.Open(path)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByReplayingFromFile", UndefinedStatusCode, "Failure opening %s", path)
+			}
+			defer f.Close()
+			var rs []recordedResponse
+			s := bufio.NewScanner(f)
+			s.Buffer(nil, 64<<20)
+			for s.Scan() {
+				if len(bytes.TrimSpace(s.Bytes())) == 0 {
+					continue
+				}
+				var rr recordedResponse
+				if err := json.Unmarshal(s.Bytes(), &rr); err != nil {
+					return NewErrorWithError(err, "autorest", "ByReplayingFromFile", UndefinedStatusCode, "Failure decoding recorded response %d in %s", len(rs)+1, path)
+				}
+				rs = append(rs, rr)
+			}
+			if err := s.Err(); err != nil {
+				return NewErrorWithError(err, "autorest", "ByReplayingFromFile", UndefinedStatusCode, "Failure reading %s", path)
+			}
+			responses = rs
+			loaded = true
+		}
+
+		if resp == nil {
+			return NewError("autorest", "ByReplayingFromFile", "Invoked with a nil http.Response")
+		}
+		if next >= len(responses) {
+			return NewError("autorest", "ByReplayingFromFile", "All %d responses recorded in %s have been replayed", len(responses), path)
+		}
+		rr := responses[next]
+		if resp.Request != nil && resp.Request.URL != nil && resp.Request.URL.String() != rr.URL {
+			return NewError("autorest", "ByReplayingFromFile", "Request URL %s does not match the recorded URL %s", resp.Request.URL, rr.URL)
+		}
+		next++
+
+		if resp.Body != nil {
+			resp.Body.Close()
+		}
+		resp.Status = rr.Status
+		resp.StatusCode = rr.StatusCode
+		resp.Header = rr.Header
+		resp.Body = ioutil.NopCloser(bytes.NewReader(rr.Body))
+		resp.ContentLength = int64(len(rr.Body))
+		return nil
+	})
+}
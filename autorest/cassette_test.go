@@ -0,0 +1,105 @@
+package autorest
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/mocks"
+)
+
+func recordResponses(t *testing.T, path string, responses ...*http.Response) {
+	for _, r := range responses {
+		if err := Respond(r, ByRecordingToFile(path), ByClosing()); err != nil {
+			t.Fatalf("autorest: ByRecordingToFile failed (%v)", err)
+		}
+	}
+}
+
+func TestByRecordingToFileRestoresBody(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByRecordingToFile(filepath.Join(t.TempDir(), "cassette.json")),
+		ByClosing())
+	if err != nil || v.Name != "Rob Pike" {
+		t.Errorf("autorest: ByRecordingToFile failed to restore the response body (%v)", err)
+	}
+}
+
+func TestByReplayingFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	first := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(first, mocks.TestHeader, "value")
+	second := mocks.NewResponseWithStatus("404 Not Found", http.StatusNotFound)
+	recordResponses(t, path, first, second)
+
+	replayer := ByReplayingFromFile(path)
+
+	v := &mocks.T{}
+	r := mocks.NewResponse()
+	err := Respond(r,
+		func(Responder) Responder { return replayer },
+		ByUnmarshallingJSON(v),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByReplayingFromFile failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || r.Header.Get(mocks.TestHeader) != "value" {
+		t.Errorf("autorest: ByReplayingFromFile failed to replay the first response -- %+v, %v", v, r.Header)
+	}
+
+	r = mocks.NewResponse()
+	if err := replayer.Respond(r); err != nil || r.StatusCode != http.StatusNotFound {
+		t.Errorf("autorest: ByReplayingFromFile failed to replay the second response -- %v (%v)", r.StatusCode, err)
+	}
+
+	if err := replayer.Respond(mocks.NewResponse()); err == nil {
+		t.Error("autorest: ByReplayingFromFile failed to return an error once all responses were replayed")
+	}
+}
+
+func TestByReplayingFromFileReturnsErrorForMismatchedURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recordResponses(t, path, mocks.NewResponse())
+
+	r := mocks.NewResponse()
+	r.Request = mocks.NewRequestForURL("https://microsoft.com/x/y/z")
+	if err := ByReplayingFromFile(path).Respond(r); err == nil {
+		t.Error("autorest: ByReplayingFromFile failed to return an error for a mismatched request URL")
+	}
+}
+
+func TestByReplayingFromFileReturnsErrorForMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+	if err := ByReplayingFromFile(path).Respond(mocks.NewResponse()); err == nil {
+		t.Error("autorest: ByReplayingFromFile failed to return an error for a missing file")
+	}
+}
+
+func TestByReplayingFromFileRereadsAfterDecodeError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recordResponses(t, path, mocks.NewResponse())
+	valid, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("autorest: Failed to read %s (%v)", path, err)
+	}
+	os.WriteFile(path, append(append([]byte(nil), valid...), "{not json\n"...), 0644)
+
+	replayer := ByReplayingFromFile(path)
+	for i := 0; i < 2; i++ {
+		if err := replayer.Respond(mocks.NewResponse()); err == nil {
+			t.Fatal("autorest: ByReplayingFromFile failed to return an error for an undecodable response")
+		}
+	}
+
+	os.WriteFile(path, valid, 0644)
+	if err := replayer.Respond(mocks.NewResponse()); err != nil {
+		t.Fatalf("autorest: ByReplayingFromFile failed after the file was repaired (%v)", err)
+	}
+	if err := replayer.Respond(mocks.NewResponse()); err == nil {
+		t.Error("autorest: ByReplayingFromFile replayed responses retained from a failed decode")
+	}
+}
@@ -0,0 +1,111 @@
+package autorest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the interface that wraps the Now and Sleep methods.
+//
+// Now returns the current time. Sleep pauses for at least the passed time.Duration.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+var (
+	// RealClock is the Clock backed by the time package.
+	RealClock Clock = realClock{}
+
+	// DefaultClock is the Clock used by time-sensitive RespondDecorators (e.g.,
+	// ByRetryAfterRespecting, ByRetryingOnStatusCode, ByMeasuringLatency, ByLoggingResponse, and
+	// ByAuditingResponse), by GetRetryAfter, and by the RateLimiter returned by NewRateLimiter.
+	// Tests may replace it with a TestClock.
+	DefaultClock = RealClock
+)
+
+type realClock struct{}
+
+// Now implements the Clock interface on realClock.
+func (rc realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep implements the Clock interface on realClock.
+func (rc realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// TestClock is a Clock whose time changes only when advanced. Sleep advances the time rather than
+// blocking, so time-dependent code runs instantly and deterministically. A TestClock is safe for
+// concurrent use.
+type TestClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+// NewTestClock returns a TestClock set to the passed time.
+func NewTestClock(t time.Time) *TestClock {
+	return &TestClock{now: t}
+}
+
+// Now implements the Clock interface on TestClock.
+func (tc *TestClock) Now() time.Time {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.now
+}
+
+// Sleep implements the Clock interface on TestClock. It records the passed time.Duration and
+// advances the time by it.
+func (tc *TestClock) Sleep(d time.Duration) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.sleeps = append(tc.sleeps, d)
+	tc.now = tc.now.Add(d)
+}
+
+// Advance moves the time forward by the passed time.Duration.
+func (tc *TestClock) Advance(d time.Duration) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.now = tc.now.Add(d)
+}
+
+// Sleeps returns, in order, the durations passed to Sleep.
+func (tc *TestClock) Sleeps() []time.Duration {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return append([]time.Duration(nil), tc.sleeps...)
+}
+
+// sleepWithContext pauses, by means of DefaultClock, for the passed time.Duration. It returns the
+// context error, if the context is done first. Clocks other than RealClock sleep in a separate
+// go-routine, which runs until their Sleep returns, so that cancellation is honoured regardless.
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var done <-chan time.Time
+	if _, ok := DefaultClock.(realClock); ok {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		done = t.C
+	} else {
+		c := make(chan time.Time, 1)
+		clock := DefaultClock
+		go func() {
+			clock.Sleep(d)
+			c <- clock.Now()
+		}()
+		done = c
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
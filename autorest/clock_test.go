@@ -0,0 +1,108 @@
+package autorest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func useTestClock(t *testing.T) *TestClock {
+	tc := NewTestClock(time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC))
+	DefaultClock = tc
+	t.Cleanup(func() { DefaultClock = RealClock })
+	return tc
+}
+
+func TestRealClockNow(t *testing.T) {
+	before := time.Now()
+	now := RealClock.Now()
+	if now.Before(before) || now.After(time.Now()) {
+		t.Errorf("autorest: RealClock#Now returned %v -- expected the current time", now)
+	}
+}
+
+func TestRealClockSleep(t *testing.T) {
+	start := time.Now()
+	RealClock.Sleep(10 * time.Millisecond)
+	if d := time.Since(start); d < 10*time.Millisecond {
+		t.Errorf("autorest: RealClock#Sleep returned after %v -- expected at least 10ms", d)
+	}
+}
+
+func TestTestClockIsFrozen(t *testing.T) {
+	now := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	tc := NewTestClock(now)
+	time.Sleep(time.Millisecond)
+	if !tc.Now().Equal(now) {
+		t.Errorf("autorest: TestClock#Now returned %v -- expected %v", tc.Now(), now)
+	}
+}
+
+func TestTestClockAdvance(t *testing.T) {
+	now := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	tc := NewTestClock(now)
+	tc.Advance(time.Hour)
+	if !tc.Now().Equal(now.Add(time.Hour)) {
+		t.Errorf("autorest: TestClock#Advance moved the time to %v -- expected %v", tc.Now(), now.Add(time.Hour))
+	}
+}
+
+func TestTestClockSleep(t *testing.T) {
+	now := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	tc := NewTestClock(now)
+	tc.Sleep(time.Minute)
+	tc.Sleep(time.Second)
+	if !tc.Now().Equal(now.Add(time.Minute + time.Second)) {
+		t.Errorf("autorest: TestClock#Sleep failed to advance the time -- now %v", tc.Now())
+	}
+	if !reflect.DeepEqual(tc.Sleeps(), []time.Duration{time.Minute, time.Second}) {
+		t.Errorf("autorest: TestClock#Sleeps returned %v", tc.Sleeps())
+	}
+}
+
+func TestSleepWithContextUsesDefaultClock(t *testing.T) {
+	tc := useTestClock(t)
+	if err := sleepWithContext(context.Background(), time.Hour); err != nil {
+		t.Errorf("autorest: sleepWithContext returned an unexpected error (%v)", err)
+	}
+	if len(tc.Sleeps()) != 1 {
+		t.Error("autorest: sleepWithContext failed to sleep by means of DefaultClock")
+	}
+}
+
+func TestSleepWithContextReturnsContextError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sleepWithContext(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("autorest: sleepWithContext returned %v -- expected context.Canceled", err)
+	}
+}
+
+// blockingClock is a Clock whose Sleep blocks until released.
+type blockingClock struct {
+	release chan struct{}
+}
+
+func (bc blockingClock) Now() time.Time {
+	return time.Time{}
+}
+
+func (bc blockingClock) Sleep(d time.Duration) {
+	<-bc.release
+}
+
+func TestSleepWithContextHonoursCancellationForAnyClock(t *testing.T) {
+	bc := blockingClock{release: make(chan struct{})}
+	DefaultClock = bc
+	defer func() {
+		DefaultClock = RealClock
+		close(bc.release)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := sleepWithContext(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("autorest: sleepWithContext returned %v -- expected context.Canceled", err)
+	}
+}
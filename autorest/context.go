@@ -0,0 +1,88 @@
+package autorest
+
+import (
+	"context"
+	"time"
+)
+
+// ContextKey is the type of the keys under which autorest stores metadata in a context.Context.
+// It is opaque: since its field is unexported, other packages cannot construct keys equal to those
+// of autorest, so the keys cannot collide with theirs.
+type ContextKey struct {
+	name string
+}
+
+// String returns the name of the ContextKey.
+func (ck ContextKey) String() string {
+	return "autorest context key " + ck.name
+}
+
+var (
+	// ContextLoggerKey is the context.Context key under which the Logger set by the WithLogger
+	// ResponderOption is stored (see LoggerFromContext).
+	ContextLoggerKey = ContextKey{"logger"}
+
+	// ContextRequestIDKey is the context.Context key under which the request ID, set by the
+	// WithRequestID ResponderOption or BySettingRequestIDFromResponse, is stored (see
+	// RequestIDFromContext).
+	ContextRequestIDKey = ContextKey{"request-id"}
+
+	// ContextCorrelationIDKey is the context.Context key under which the correlation ID, set by the
+	// WithCorrelationID ResponderOption or BySettingRequestIDFromResponse, is stored (see
+	// CorrelationIDFromContext).
+	ContextCorrelationIDKey = ContextKey{"correlation-id"}
+
+	// ContextClientRequestIDKey is the context.Context key under which the client request ID, set
+	// by ByAddingRequestID, is stored (see ClientRequestIDFromContext).
+	ContextClientRequestIDKey = ContextKey{"client-request-id"}
+
+	// ContextStartTimeKey is the context.Context key under which the time the request was sent, set
+	// by ContextWithStartTime (e.g., by the WithStartTime SendDecorator), is stored (see
+	// StartTimeFromContext).
+	ContextStartTimeKey = ContextKey{"start-time"}
+
+	// contextResendCountKey is the context.Context key under which ByRecordingRetryCount stores the
+	// *int32 that resendRequest increments for each re-sent request.
+	contextResendCountKey = ContextKey{"resend-count"}
+)
+
+// LoggerFromContext returns the Logger set by the WithLogger ResponderOption or nil if none was
+// set.
+func LoggerFromContext(ctx context.Context) Logger {
+	l, _ := ctx.Value(ContextLoggerKey).(Logger)
+	return l
+}
+
+// RequestIDFromContext returns the request ID set by the WithRequestID ResponderOption or
+// BySettingRequestIDFromResponse, or the empty string if none was set.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ContextRequestIDKey).(string)
+	return id
+}
+
+// CorrelationIDFromContext returns the correlation ID set by the WithCorrelationID ResponderOption
+// or BySettingRequestIDFromResponse, or the empty string if none was set.
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ContextCorrelationIDKey).(string)
+	return id
+}
+
+// ClientRequestIDFromContext returns the client request ID set by ByAddingRequestID or the empty
+// string if none was set.
+func ClientRequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ContextClientRequestIDKey).(string)
+	return id
+}
+
+// ContextWithStartTime returns a copy of the passed context.Context carrying t as the time at which
+// the request was sent.
+func ContextWithStartTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, ContextStartTimeKey, t)
+}
+
+// StartTimeFromContext returns the request start time carried by the passed context.Context or the
+// zero time.Time if none was set.
+func StartTimeFromContext(ctx context.Context) time.Time {
+	t, _ := ctx.Value(ContextStartTimeKey).(time.Time)
+	return t
+}
@@ -0,0 +1,53 @@
+package autorest
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestContextKeysAreDistinct(t *testing.T) {
+	keys := []ContextKey{ContextLoggerKey, ContextRequestIDKey, ContextCorrelationIDKey, ContextClientRequestIDKey, ContextStartTimeKey, contextResendCountKey}
+	seen := map[ContextKey]bool{}
+	for _, k := range keys {
+		if seen[k] {
+			t.Errorf("autorest: Context key %v is not unique", k)
+		}
+		seen[k] = true
+	}
+}
+
+func TestContextKeysDoNotCollideWithOtherKeys(t *testing.T) {
+	type key string
+	ctx := context.WithValue(context.Background(), key("request-id"), "other")
+	if RequestIDFromContext(ctx) != "" {
+		t.Error("autorest: RequestIDFromContext returned a value stored under another package's key")
+	}
+}
+
+func TestCorrelationIDFromContext(t *testing.T) {
+	ctx := WithCorrelationID("correlation-id")(context.Background())
+	if id := CorrelationIDFromContext(ctx); id != "correlation-id" {
+		t.Errorf("autorest: CorrelationIDFromContext returned %q -- expected correlation-id", id)
+	}
+}
+
+func TestCorrelationIDFromContextReturnsEmptyIfMissing(t *testing.T) {
+	if CorrelationIDFromContext(context.Background()) != "" {
+		t.Error("autorest: CorrelationIDFromContext reported a correlation ID in an empty context")
+	}
+}
+
+func TestClientRequestIDFromContextReturnsEmptyIfMissing(t *testing.T) {
+	if ClientRequestIDFromContext(context.Background()) != "" {
+		t.Error("autorest: ClientRequestIDFromContext reported a client request ID in an empty context")
+	}
+}
+
+func TestStartTimeFromContextUsesContextStartTimeKey(t *testing.T) {
+	now := time.Now()
+	ctx := ContextWithStartTime(context.Background(), now)
+	if ctx.Value(ContextStartTimeKey) != now {
+		t.Error("autorest: ContextWithStartTime failed to store the time under ContextStartTimeKey")
+	}
+}
@@ -1,7 +1,10 @@
 package autorest
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 )
 
 const (
@@ -98,6 +101,11 @@ func (be baseError) Original() error {
 	return be.original
 }
 
+// Unwrap returns the original error, if any, so that errors.Is and errors.As examine it.
+func (be baseError) Unwrap() error {
+	return be.original
+}
+
 // Error returns the same formatted string as String.
 func (be baseError) Error() string {
 	return be.String()
@@ -111,3 +119,113 @@ func (be baseError) String() string {
 	}
 	return fmt.Sprintf("%s:%s %v %s -- Original Error: %v", be.packageType, be.method, be.statusCode, be.message, be.original)
 }
+
+// ServiceError describes the error object Azure services return, wrapped within an "error" field,
+// in the body of failed responses.
+type ServiceError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// DetailedError is an Error that, in addition to the details every Error carries, may carry the
+// ServiceError parsed from the body of the failing response and the response itself.
+type DetailedError struct {
+	baseError
+
+	// ServiceError is the error returned by the service, if any, and nil otherwise.
+	ServiceError *ServiceError
+
+	// Response is the http.Response that led to the error, if known, and nil otherwise. Its body
+	// may already have been read or closed.
+	Response *http.Response
+}
+
+// NewDetailedError creates a new DetailedError from the passed ServiceError (which may be nil),
+// packageType, method, statusCode, and message. message is treated as a format string to which the
+// optional args apply.
+func NewDetailedError(serviceError *ServiceError, packageType string, method string, statusCode int, message string, args ...interface{}) *DetailedError {
+	return &DetailedError{
+		baseError: baseError{
+			packageType: packageType,
+			method:      method,
+			statusCode:  statusCode,
+			message:     fmt.Sprintf(message, args...),
+		},
+		ServiceError: serviceError,
+	}
+}
+
+// Error returns the same formatted string as String.
+func (de DetailedError) Error() string {
+	return de.String()
+}
+
+// String returns a formatted containing all available details (i.e., PackageType, Method,
+// StatusCode, Message, and the ServiceError (if any)).
+func (de DetailedError) String() string {
+	if de.ServiceError == nil {
+		return de.baseError.String()
+	}
+	return fmt.Sprintf("%s -- Service Error: %s: %s", de.baseError.String(), de.ServiceError.Code, de.ServiceError.Message)
+}
+
+// RetriableError is implemented by errors able to report whether the failed operation may succeed
+// if attempted again (i.e., whether the failure is transient rather than terminal).
+type RetriableError interface {
+	error
+
+	// IsRetriable should return true if the failed operation may be retried.
+	IsRetriable() bool
+}
+
+// retriableStatusCodes are the HTTP status codes that indicate a transient failure.
+var retriableStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// IsRetriable returns true if the StatusCode of the DetailedError indicates a transient failure
+// (i.e., 408, 429, 500, 502, 503, or 504) and false for all others (e.g., 400 or 401).
+func (de DetailedError) IsRetriable() bool {
+	return containsInt(retriableStatusCodes, de.StatusCode())
+}
+
+// IsRetriable returns true if the first RetriableError found in the chain of the passed error (see
+// errors.As) reports itself as retriable, and false otherwise (including when none is found).
+func IsRetriable(err error) bool {
+	var re RetriableError
+	return errors.As(err, &re) && re.IsRetriable()
+}
+
+// ResponseFromError returns the Response of the first DetailedError found in the chain of the
+// passed error (see errors.As), if any, and whether one carrying a Response was found.
+func ResponseFromError(err error) (*http.Response, bool) {
+	var de *DetailedError
+	if errors.As(err, &de) && de.Response != nil {
+		return de.Response, true
+	}
+	return nil, false
+}
+
+// JSONBodyError is an error carrying the value decoded from the JSON body of a failed response
+// (see ByUnmarshallingJSONOrError).
+type JSONBodyError struct {
+	// StatusCode is the HTTP Response StatusCode of the failed response.
+	StatusCode int
+
+	// Value is the value, passed to ByUnmarshallingJSONOrError, into which the body was decoded.
+	Value interface{}
+}
+
+// Error returns the status code and the JSON encoding of the Value.
+func (jbe JSONBodyError) Error() string {
+	b, err := json.Marshal(jbe.Value)
+	if err != nil {
+		return fmt.Sprintf("autorest: HTTP %v (%v)", jbe.StatusCode, jbe.Value)
+	}
+	return fmt.Sprintf("autorest: HTTP %v %s", jbe.StatusCode, b)
+}
@@ -1,6 +1,7 @@
 package autorest
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -130,3 +131,102 @@ func TestErrorStringSkipsOriginal(t *testing.T) {
 			`.*Original.*`, e.String())
 	}
 }
+
+func TestDetailedErrorImplementsError(t *testing.T) {
+	var e interface{} = NewDetailedError(nil, "packageType", "method", http.StatusBadRequest, "message")
+
+	if _, ok := e.(Error); !ok {
+		t.Errorf("autorest: DetailedError does not implement the Error interface")
+	}
+}
+
+func TestDetailedErrorStringIncludesServiceError(t *testing.T) {
+	e := NewDetailedError(&ServiceError{Code: "code", Message: "service message"}, "packageType", "method", http.StatusBadRequest, "message")
+
+	if matched, _ := regexp.MatchString(`.*code.*service message.*`, e.Error()); !matched {
+		t.Errorf("autorest: DetailedError#String failed to include the service error -- received %v", e.Error())
+	}
+}
+
+func TestJSONBodyErrorIncludesValue(t *testing.T) {
+	e := JSONBodyError{StatusCode: http.StatusNotFound, Value: &ServiceError{Code: "NotFound"}}
+
+	if matched, _ := regexp.MatchString(`.*404.*"code":"NotFound".*`, e.Error()); !matched {
+		t.Errorf("autorest: JSONBodyError#Error failed to include the status code and value -- received %v", e.Error())
+	}
+}
+
+func TestErrorUnwrapReturnsOriginal(t *testing.T) {
+	original := fmt.Errorf("original")
+	e := NewErrorWithError(original, "packageType", "method", http.StatusBadRequest, "message")
+
+	if !errors.Is(e, original) {
+		t.Errorf("autorest: Error failed to unwrap to the original error")
+	}
+}
+
+func TestResponseFromError(t *testing.T) {
+	r := &http.Response{StatusCode: http.StatusBadRequest}
+	de := NewDetailedError(nil, "packageType", "method", http.StatusBadRequest, "message")
+	de.Response = r
+	var err error = de
+
+	resp, ok := ResponseFromError(fmt.Errorf("wrapped: %w", err))
+	if !ok || resp != r {
+		t.Errorf("autorest: ResponseFromError failed to find the response in a wrapped DetailedError")
+	}
+}
+
+func TestResponseFromErrorReturnsFalseWithoutResponse(t *testing.T) {
+	errs := []error{
+		nil,
+		fmt.Errorf("plain"),
+		NewDetailedError(nil, "packageType", "method", http.StatusBadRequest, "message"),
+	}
+	for _, err := range errs {
+		if resp, ok := ResponseFromError(err); ok || resp != nil {
+			t.Errorf("autorest: ResponseFromError unexpectedly found a response in %v", err)
+		}
+	}
+}
+
+func TestDetailedErrorImplementsRetriableError(t *testing.T) {
+	var e interface{} = NewDetailedError(nil, "packageType", "method", http.StatusServiceUnavailable, "message")
+
+	if _, ok := e.(RetriableError); !ok {
+		t.Errorf("autorest: DetailedError does not implement the RetriableError interface")
+	}
+}
+
+func TestDetailedErrorIsRetriable(t *testing.T) {
+	cases := map[int]bool{
+		http.StatusTooManyRequests:    true,
+		http.StatusServiceUnavailable: true,
+		http.StatusGatewayTimeout:     true,
+		http.StatusBadRequest:         false,
+		http.StatusUnauthorized:       false,
+		http.StatusNotFound:           false,
+		UndefinedStatusCode:           false,
+	}
+	for code, expected := range cases {
+		e := NewDetailedError(nil, "packageType", "method", code, "message")
+		if e.IsRetriable() != expected {
+			t.Errorf("autorest: DetailedError#IsRetriable returned %v for status code %v, expected %v", !expected, code, expected)
+		}
+	}
+}
+
+func TestIsRetriable(t *testing.T) {
+	var retriable error = NewDetailedError(nil, "packageType", "method", http.StatusTooManyRequests, "message")
+	var terminal error = NewDetailedError(nil, "packageType", "method", http.StatusBadRequest, "message")
+
+	if !IsRetriable(fmt.Errorf("wrapped: %w", retriable)) {
+		t.Errorf("autorest: IsRetriable returned false for a wrapped retriable error")
+	}
+	if IsRetriable(terminal) {
+		t.Errorf("autorest: IsRetriable returned true for a terminal error")
+	}
+	if IsRetriable(fmt.Errorf("plain")) || IsRetriable(nil) {
+		t.Errorf("autorest: IsRetriable returned true for an error not implementing RetriableError")
+	}
+}
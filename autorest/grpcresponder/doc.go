@@ -0,0 +1,8 @@
+/*
+Package grpcresponder provides RespondDecorators that translate Azure HTTP responses for services
+built on gRPC (e.g., those using gRPC-HTTP transcoding).
+
+Note: To avoid a gRPC dependency for all users, the package content is only built when the grpc
+build tag is set (e.g., go build -tags grpc).
+*/
+package grpcresponder
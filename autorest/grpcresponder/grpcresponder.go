@@ -0,0 +1,74 @@
+//go:build grpc
+// +build grpc
+
+package grpcresponder
+
+import (
+	"net/http"
+
+	"github.com/Azure/go-autorest/autorest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ByMappingHTTPToGRPCStatus returns a RespondDecorator that, after invoking the passed Responder,
+// replaces the result for responses whose StatusCode is not 2xx with a gRPC status error whose code
+// is chosen by mapper (or DefaultHTTPToGRPCCode, if mapper is nil). The status message carries the
+// response status and, if any, the error returned by the passed Responder. Results for successful
+// responses pass along unmodified.
+func ByMappingHTTPToGRPCStatus(mapper func(int) codes.Code) autorest.RespondDecorator {
+	if mapper == nil {
+		mapper = DefaultHTTPToGRPCCode
+	}
+	return func(r autorest.Responder) autorest.Responder {
+		return autorest.ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if resp == nil || (resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices) {
+				return err
+			}
+			if err != nil {
+				return status.Errorf(mapper(resp.StatusCode), "%s (%v)", resp.Status, err)
+			}
+			return status.Errorf(mapper(resp.StatusCode), "%s", resp.Status)
+		})
+	}
+}
+
+// DefaultHTTPToGRPCCode maps the passed HTTP status code to a gRPC status code following the HTTP
+// mapping documented for google.rpc.Code. Unlisted 4xx status codes map to FailedPrecondition,
+// unlisted 5xx status codes to Internal, and all others to Unknown.
+func DefaultHTTPToGRPCCode(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return codes.OutOfRange
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	switch {
+	case statusCode >= 400 && statusCode < 500:
+		return codes.FailedPrecondition
+	case statusCode >= 500 && statusCode < 600:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,73 @@
+//go:build grpc
+// +build grpc
+
+package grpcresponder
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/mocks"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestByMappingHTTPToGRPCStatus(t *testing.T) {
+	r := mocks.NewResponseWithStatus("404 Not Found", http.StatusNotFound)
+	err := autorest.Respond(r,
+		autorest.WithErrorUnlessOK(),
+		ByMappingHTTPToGRPCStatus(nil),
+		autorest.ByClosing())
+	s, ok := status.FromError(err)
+	if !ok || s.Code() != codes.NotFound {
+		t.Errorf("grpcresponder: ByMappingHTTPToGRPCStatus returned %v -- expected a NotFound status", err)
+	}
+}
+
+func TestByMappingHTTPToGRPCStatusUsesMapper(t *testing.T) {
+	r := mocks.NewResponseWithStatus("500 Internal Server Error", http.StatusInternalServerError)
+	err := autorest.Respond(r,
+		ByMappingHTTPToGRPCStatus(func(int) codes.Code { return codes.DataLoss }),
+		autorest.ByClosing())
+	if status.Code(err) != codes.DataLoss {
+		t.Errorf("grpcresponder: ByMappingHTTPToGRPCStatus ignored the mapper -- returned %v", err)
+	}
+}
+
+func TestByMappingHTTPToGRPCStatusIgnoresSuccess(t *testing.T) {
+	r := mocks.NewResponse()
+	e := fmt.Errorf("grpcresponder: Faux Respond Error")
+	err := autorest.Respond(r,
+		(func() autorest.RespondDecorator {
+			return func(autorest.Responder) autorest.Responder {
+				return autorest.ResponderFunc(func(*http.Response) error { return e })
+			}
+		})(),
+		ByMappingHTTPToGRPCStatus(nil),
+		autorest.ByClosing())
+	if err != e {
+		t.Errorf("grpcresponder: ByMappingHTTPToGRPCStatus modified the result of a successful response -- returned %v", err)
+	}
+}
+
+func TestDefaultHTTPToGRPCCode(t *testing.T) {
+	cases := map[int]codes.Code{
+		http.StatusBadRequest:         codes.InvalidArgument,
+		http.StatusUnauthorized:       codes.Unauthenticated,
+		http.StatusForbidden:          codes.PermissionDenied,
+		http.StatusConflict:           codes.Aborted,
+		http.StatusTooManyRequests:    codes.ResourceExhausted,
+		http.StatusServiceUnavailable: codes.Unavailable,
+		http.StatusGatewayTimeout:     codes.DeadlineExceeded,
+		http.StatusTeapot:             codes.FailedPrecondition,
+		http.StatusBadGateway:         codes.Internal,
+		http.StatusPermanentRedirect:  codes.Unknown,
+	}
+	for statusCode, expected := range cases {
+		if c := DefaultHTTPToGRPCCode(statusCode); c != expected {
+			t.Errorf("grpcresponder: DefaultHTTPToGRPCCode(%d) returned %v -- expected %v", statusCode, c, expected)
+		}
+	}
+}
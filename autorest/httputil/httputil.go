@@ -0,0 +1,62 @@
+/*
+Package httputil adapts autorest Responders to and from net/http Handlers so that autorest
+decorators may be composed with net/http middleware (e.g., in reverse proxies that post-process
+responses).
+*/
+package httputil
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// ResponderAsHandler returns an http.Handler that serves the request by means of next, records the
+// response next writes, applies the passed Responder to the recorded http.Response, and then writes
+// the, possibly modified, response to the client. If the Responder returns an error, the client
+// instead receives an HTTP 502 Bad Gateway describing the error.
+func ResponderAsHandler(r autorest.Responder, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, req)
+		resp := rec.Result()
+		resp.Request = req
+		defer resp.Body.Close()
+
+		if err := r.Respond(resp); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		h := w.Header()
+		for k, v := range resp.Header {
+			h[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		if resp.Body != nil {
+			io.Copy(w, resp.Body)
+		}
+	})
+}
+
+// HandlerAsResponder returns an autorest.Responder that serves the request of the passed
+// http.Response by means of h and replaces the passed http.Response with the response h writes. The
+// body of the replaced http.Response is closed. It returns an error if the passed http.Response
+// lacks a request.
+func HandlerAsResponder(h http.Handler) autorest.Responder {
+	return autorest.ResponderFunc(func(resp *http.Response) error {
+		if resp == nil || resp.Request == nil {
+			return autorest.NewError("httputil", "HandlerAsResponder", "Invoked without an http.Request to serve")
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, resp.Request)
+		result := rec.Result()
+		result.Request = resp.Request
+		if resp.Body != nil {
+			resp.Body.Close()
+		}
+		*resp = *result
+		return nil
+	})
+}
@@ -0,0 +1,82 @@
+package httputil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/mocks"
+)
+
+func newTestHandler(status int, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(mocks.TestHeader, "handler")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	})
+}
+
+func TestResponderAsHandlerAppliesResponder(t *testing.T) {
+	var seen int
+	r := autorest.CreateResponder(
+		autorest.ByObservingStatusCode(func(code int) { seen = code }),
+		func(r autorest.Responder) autorest.Responder {
+			return autorest.ResponderFunc(func(resp *http.Response) error {
+				resp.Header.Set("X-Processed", "true")
+				return r.Respond(resp)
+			})
+		})
+
+	w := httptest.NewRecorder()
+	ResponderAsHandler(r, newTestHandler(http.StatusCreated, "content")).ServeHTTP(w, mocks.NewRequest())
+	if seen != http.StatusCreated {
+		t.Errorf("httputil: ResponderAsHandler passed status %d to the Responder -- expected %d", seen, http.StatusCreated)
+	}
+	if w.Code != http.StatusCreated || w.Body.String() != "content" {
+		t.Errorf("httputil: ResponderAsHandler wrote %d %q -- expected %d %q", w.Code, w.Body.String(), http.StatusCreated, "content")
+	}
+	if w.Header().Get(mocks.TestHeader) != "handler" || w.Header().Get("X-Processed") != "true" {
+		t.Errorf("httputil: ResponderAsHandler failed to write the processed headers -- received %v", w.Header())
+	}
+}
+
+func TestResponderAsHandlerWritesErrors(t *testing.T) {
+	r := autorest.CreateResponder(autorest.WithErrorUnlessOK())
+
+	w := httptest.NewRecorder()
+	ResponderAsHandler(r, newTestHandler(http.StatusInternalServerError, "failed")).ServeHTTP(w, mocks.NewRequest())
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("httputil: ResponderAsHandler wrote status %d for a Responder error -- expected %d", w.Code, http.StatusBadGateway)
+	}
+}
+
+func TestHandlerAsResponderReplacesResponse(t *testing.T) {
+	resp := mocks.NewResponse()
+	original := resp.Body.(*mocks.Body)
+	req := resp.Request
+
+	if err := HandlerAsResponder(newTestHandler(http.StatusAccepted, "content")).Respond(resp); err != nil {
+		t.Fatalf("httputil: HandlerAsResponder returned an unexpected error (%v)", err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusAccepted || string(b) != "content" || resp.Header.Get(mocks.TestHeader) != "handler" {
+		t.Errorf("httputil: HandlerAsResponder failed to replace the response -- received %d %q", resp.StatusCode, b)
+	}
+	if resp.Request != req {
+		t.Error("httputil: HandlerAsResponder failed to keep the request of the response")
+	}
+	if original.IsOpen() {
+		t.Error("httputil: HandlerAsResponder failed to close the body of the replaced response")
+	}
+}
+
+func TestHandlerAsResponderReturnsErrorWithoutRequest(t *testing.T) {
+	resp := mocks.NewResponse()
+	resp.Request = nil
+	if err := HandlerAsResponder(newTestHandler(http.StatusOK, "")).Respond(resp); err == nil {
+		t.Error("httputil: HandlerAsResponder failed to return an error for a response without a request")
+	}
+}
@@ -0,0 +1,34 @@
+package autorest
+
+// Middleware bundles the PrepareDecorator and RespondDecorator implementing both sides of a single
+// cross-cutting concern (e.g., authorization, tracing, or retry). Either may be nil.
+type Middleware struct {
+	// Prepare is the PrepareDecorator that prepares the outgoing http.Request.
+	Prepare PrepareDecorator
+
+	// Request is the RespondDecorator that handles the http.Response returned for the request.
+	Request RespondDecorator
+}
+
+// ApplyMiddleware merges the passed, possibly empty, set of Middleware into a single
+// PrepareDecorator and a single RespondDecorator. Each applies the corresponding decorators of the
+// Middleware in the order received, skipping those that are nil.
+func ApplyMiddleware(middlewares ...Middleware) (PrepareDecorator, RespondDecorator) {
+	var prepares []PrepareDecorator
+	var responds []RespondDecorator
+	for _, m := range middlewares {
+		if m.Prepare != nil {
+			prepares = append(prepares, m.Prepare)
+		}
+		if m.Request != nil {
+			responds = append(responds, m.Request)
+		}
+	}
+
+	return func(p Preparer) Preparer {
+			return DecoratePreparer(p, prepares...)
+		},
+		func(r Responder) Responder {
+			return DecorateResponder(r, responds...)
+		}
+}
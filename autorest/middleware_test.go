@@ -0,0 +1,63 @@
+package autorest
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/mocks"
+)
+
+func TestApplyMiddlewareRunsDecoratorsInOrder(t *testing.T) {
+	s := ""
+
+	p := func(n int) PrepareDecorator {
+		return func(p Preparer) Preparer {
+			return PreparerFunc(func(r *http.Request) (*http.Request, error) {
+				r, err := p.Prepare(r)
+				s += fmt.Sprintf("p%d", n)
+				return r, err
+			})
+		}
+	}
+	r := func(n int) RespondDecorator {
+		return func(r Responder) Responder {
+			return ResponderFunc(func(resp *http.Response) error {
+				err := r.Respond(resp)
+				s += fmt.Sprintf("r%d", n)
+				return err
+			})
+		}
+	}
+
+	prepare, respond := ApplyMiddleware(
+		Middleware{Prepare: p(1), Request: r(1)},
+		Middleware{Prepare: p(2)},
+		Middleware{Request: r(3)})
+
+	_, err := Prepare(mocks.NewRequest(), prepare)
+	if err != nil {
+		t.Errorf("autorest: ApplyMiddleware PrepareDecorator failed (%v)", err)
+	}
+	err = Respond(mocks.NewResponse(), respond)
+	if err != nil {
+		t.Errorf("autorest: ApplyMiddleware RespondDecorator failed (%v)", err)
+	}
+
+	if s != "p1p2r1r3" {
+		t.Errorf("autorest: ApplyMiddleware invoked decorators in an incorrect order; expected 'p1p2r1r3', received '%s'", s)
+	}
+}
+
+func TestApplyMiddlewareAcceptsNoMiddleware(t *testing.T) {
+	prepare, respond := ApplyMiddleware()
+
+	r := mocks.NewRequest()
+	req, err := Prepare(r, prepare)
+	if err != nil || req != r {
+		t.Errorf("autorest: ApplyMiddleware without Middleware modified the request (%v)", err)
+	}
+	if err = Respond(mocks.NewResponse(), respond); err != nil {
+		t.Errorf("autorest: ApplyMiddleware without Middleware failed (%v)", err)
+	}
+}
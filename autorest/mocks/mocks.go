@@ -15,6 +15,7 @@ type Body struct {
 	b             []byte
 	isOpen        bool
 	closeAttempts int
+	closeErr      error
 }
 
 // NewBody creates a new instance of Body.
@@ -41,7 +42,7 @@ func (body *Body) Close() error {
 		body.isOpen = false
 		body.closeAttempts++
 	}
-	return nil
+	return body.closeErr
 }
 
 // CloseAttempts returns the number of times Close was called.
@@ -49,6 +50,11 @@ func (body *Body) CloseAttempts() int {
 	return body.closeAttempts
 }
 
+// SetCloseError sets the error Close should return.
+func (body *Body) SetCloseError(err error) {
+	body.closeErr = err
+}
+
 // IsOpen returns true if the Body has not been closed, false otherwise.
 func (body *Body) IsOpen() bool {
 	return body.isOpen
@@ -0,0 +1,99 @@
+package autorest
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// MultipartPart is a single part of a multipart/mixed response (see
+// ByUnmarshallingMultipartResponse).
+type MultipartPart struct {
+	// StatusCode is the status code of the HTTP response embedded in the part (i.e., for parts of
+	// type application/http, as returned by batch APIs) and zero otherwise.
+	StatusCode int
+
+	// Headers holds the headers of the embedded HTTP response, if any, and those of the part
+	// otherwise.
+	Headers http.Header
+
+	// Body holds the body of the embedded HTTP response, if any, and the content of the part
+	// otherwise.
+	Body []byte
+
+	// Err is the error, if any, that occurred reading or parsing the part. Body then holds the raw
+	// content of the part, if it could be read.
+	Err error
+}
+
+// ByUnmarshallingMultipartResponse returns a RespondDecorator that splits a multipart/mixed
+// response Body into its parts, using the boundary given by the Content-Type header, and stores them
+// in the slice pointed to by parts. Parts of type application/http are parsed as HTTP responses.
+// A malformed part is recorded with its Err set rather than aborting the parse; the decorator
+// returns an error only if the response is not multipart or its structure cannot be read.
+func ByUnmarshallingMultipartResponse(parts *[]MultipartPart) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+				return err
+			}
+			mt, params, err := mime.ParseMediaType(resp.Header.Get(headerContentType))
+			if err != nil || !strings.HasPrefix(mt, "multipart/") || params["boundary"] == "" {
+				return NewError("autorest", "ByUnmarshallingMultipartResponse", "Response is not a multipart response (Content-Type %q)",
+					resp.Header.Get(headerContentType))
+			}
+
+			var ps []MultipartPart
+			mr := multipart.NewReader(resp.Body, params["boundary"])
+			for {
+				p, err := mr.NextPart()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					return NewErrorWithError(err, "autorest", "ByUnmarshallingMultipartResponse", resp.StatusCode, "Failure reading part %d", len(ps))
+				}
+				ps = append(ps, readMultipartPart(p))
+			}
+			*parts = ps
+			return nil
+		})
+	}
+}
+
+// readMultipartPart reads the passed part into a MultipartPart, parsing its content as an HTTP
+// response if it is of type application/http.
+func readMultipartPart(p *multipart.Part) MultipartPart {
+	defer p.Close()
+	mp := MultipartPart{Headers: http.Header(p.Header)}
+	b, err := ioutil.ReadAll(p)
+	mp.Body = b
+	if err != nil {
+		mp.Err = NewErrorWithError(err, "autorest", "ByUnmarshallingMultipartResponse", UndefinedStatusCode, "Failure reading part")
+		return mp
+	}
+	if mt, _, _ := mime.ParseMediaType(p.Header.Get(headerContentType)); mt != "application/http" {
+		return mp
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), nil)
+	if err != nil {
+		mp.Err = NewErrorWithError(err, "autorest", "ByUnmarshallingMultipartResponse", UndefinedStatusCode, "Failure parsing embedded HTTP response")
+		return mp
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		mp.Err = NewErrorWithError(err, "autorest", "ByUnmarshallingMultipartResponse", resp.StatusCode, "Failure reading embedded HTTP response body")
+		return mp
+	}
+	mp.StatusCode = resp.StatusCode
+	mp.Headers = resp.Header
+	mp.Body = body
+	return mp
+}
@@ -0,0 +1,90 @@
+package autorest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/mocks"
+)
+
+const multipartT = "--batch\r\n" +
+	"Content-Type: application/http\r\n" +
+	"\r\n" +
+	"HTTP/1.1 200 OK\r\n" +
+	"Content-Type: application/json\r\n" +
+	"\r\n" +
+	`{"name":"Rob Pike"}` + "\r\n" +
+	"--batch\r\n" +
+	"Content-Type: application/http\r\n" +
+	"\r\n" +
+	"not an HTTP response\r\n" +
+	"--batch\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"plain text\r\n" +
+	"--batch--\r\n"
+
+func newMultipartResponse(content string) *http.Response {
+	r := mocks.NewResponseWithContent(content)
+	mocks.SetResponseHeader(r, headerContentType, "multipart/mixed; boundary=batch")
+	return r
+}
+
+func TestByUnmarshallingMultipartResponse(t *testing.T) {
+	var parts []MultipartPart
+	err := Respond(newMultipartResponse(multipartT),
+		ByUnmarshallingMultipartResponse(&parts),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingMultipartResponse failed (%v)", err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("autorest: ByUnmarshallingMultipartResponse returned %d parts, expected 3", len(parts))
+	}
+
+	p := parts[0]
+	if p.Err != nil || p.StatusCode != http.StatusOK || p.Headers.Get(headerContentType) != "application/json" || string(p.Body) != `{"name":"Rob Pike"}` {
+		t.Errorf("autorest: ByUnmarshallingMultipartResponse failed to parse an embedded HTTP response -- received %+v", p)
+	}
+	p = parts[2]
+	if p.Err != nil || p.StatusCode != 0 || p.Headers.Get(headerContentType) != "text/plain" || string(p.Body) != "plain text" {
+		t.Errorf("autorest: ByUnmarshallingMultipartResponse failed to read a plain part -- received %+v", p)
+	}
+}
+
+func TestByUnmarshallingMultipartResponseFlagsMalformedParts(t *testing.T) {
+	var parts []MultipartPart
+	err := Respond(newMultipartResponse(multipartT),
+		ByUnmarshallingMultipartResponse(&parts),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingMultipartResponse aborted on a malformed part (%v)", err)
+	}
+	if len(parts) != 3 || parts[1].Err == nil || string(parts[1].Body) != "not an HTTP response" {
+		t.Errorf("autorest: ByUnmarshallingMultipartResponse failed to flag the malformed part -- received %+v", parts)
+	}
+}
+
+func TestByUnmarshallingMultipartResponseReturnsErrorIfNotMultipart(t *testing.T) {
+	var parts []MultipartPart
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, headerContentType, "application/json")
+	err := Respond(r,
+		ByUnmarshallingMultipartResponse(&parts),
+		ByClosing())
+	if err == nil {
+		t.Errorf("autorest: ByUnmarshallingMultipartResponse failed to return an error for a non-multipart response")
+	}
+}
+
+func TestByUnmarshallingMultipartResponseReturnsErrorForTruncatedBody(t *testing.T) {
+	var parts []MultipartPart
+	truncated := multipartT[:strings.LastIndex(multipartT, "--batch--")]
+	err := Respond(newMultipartResponse(truncated),
+		ByUnmarshallingMultipartResponse(&parts),
+		ByClosing())
+	if err == nil {
+		t.Errorf("autorest: ByUnmarshallingMultipartResponse failed to return an error for a truncated body")
+	}
+}
@@ -2,6 +2,7 @@ package autorest
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -16,6 +17,7 @@ const (
 
 	headerAuthorization = "Authorization"
 	headerContentType   = "Content-Type"
+	headerIfMatch       = "If-Match"
 	headerUserAgent     = "User-Agent"
 )
 
@@ -102,6 +104,63 @@ func WithBearerAuthorization(token string) PrepareDecorator {
 	return WithHeader(headerAuthorization, fmt.Sprintf("Bearer %s", token))
 }
 
+// WithIfMatch returns a PrepareDecorator that adds an HTTP If-Match header whose value is the ETag
+// pointed to by etag at the time the request is prepared (e.g., as captured by ByCapturingETag).
+// Unquoted ETags are quoted; weak ETags and "*" are used as is. No header is added if the ETag is
+// empty.
+func WithIfMatch(etag *string) PrepareDecorator {
+	return func(p Preparer) Preparer {
+		return PreparerFunc(func(r *http.Request) (*http.Request, error) {
+			r, err := p.Prepare(r)
+			if err == nil && *etag != "" {
+				v := *etag
+				if v != "*" && !strings.HasPrefix(v, `"`) && !strings.HasPrefix(v, "W/") {
+					v = `"` + v + `"`
+				}
+				if r.Header == nil {
+					r.Header = make(http.Header)
+				}
+				r.Header.Set(headerIfMatch, v)
+			}
+			return r, err
+		})
+	}
+}
+
+// WithRequestContentHash returns a PrepareDecorator that computes, using the named algorithm (one
+// of HashMD5, HashSHA256, or HashCRC64), the digest of the request body, stores it, base64 encoded,
+// in the string pointed to by hash, and sets the matching header (i.e., Content-MD5,
+// x-ms-content-sha256, or x-ms-content-crc64) to that value. The body is restored after it is read.
+// Requests without a body are left unmodified. Paired with ByComputingResponseContentHash, it covers
+// both directions of an exchange.
+func WithRequestContentHash(algorithm string, hash *string) PrepareDecorator {
+	return func(p Preparer) Preparer {
+		return PreparerFunc(func(r *http.Request) (*http.Request, error) {
+			r, err := p.Prepare(r)
+			if err != nil || r.Body == nil || r.Body == http.NoBody {
+				return r, err
+			}
+			h := newContentHash(algorithm)
+			if h == nil {
+				return r, NewError("autorest", "WithRequestContentHash", "Unsupported hash algorithm %s", algorithm)
+			}
+			b, err := ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+			if err != nil {
+				return r, NewErrorWithError(err, "autorest", "WithRequestContentHash", UndefinedStatusCode, "Failure reading request body")
+			}
+			h.Write(b)
+			*hash = base64.StdEncoding.EncodeToString(contentHashSum(h))
+			if r.Header == nil {
+				r.Header = make(http.Header)
+			}
+			r.Header.Set(contentHashHeader(algorithm), *hash)
+			return r, nil
+		})
+	}
+}
+
 // AsContentType returns a PrepareDecorator that adds an HTTP Content-Type header whose value
 // is the passed contentType.
 func AsContentType(contentType string) PrepareDecorator {
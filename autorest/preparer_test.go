@@ -1,6 +1,8 @@
 package autorest
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -323,6 +325,39 @@ func TestWithBearerAuthorization(t *testing.T) {
 	}
 }
 
+func TestWithIfMatch(t *testing.T) {
+	cases := map[string]string{
+		"v1":     `"v1"`,
+		`"v1"`:   `"v1"`,
+		`W/"v1"`: `W/"v1"`,
+		"*":      "*",
+	}
+	for etag, expected := range cases {
+		r, err := Prepare(mocks.NewRequest(), WithIfMatch(&etag))
+		if err != nil {
+			t.Fatalf("autorest: WithIfMatch failed (%v)", err)
+		}
+		if r.Header.Get(headerIfMatch) != expected {
+			t.Errorf("autorest: WithIfMatch set %s=%s for ETag %s, expected %s", headerIfMatch, r.Header.Get(headerIfMatch), etag, expected)
+		}
+	}
+}
+
+func TestWithIfMatchReadsETagWhenPrepared(t *testing.T) {
+	var etag string
+	p := CreatePreparer(WithIfMatch(&etag))
+
+	r, _ := p.Prepare(mocks.NewRequest())
+	if _, ok := r.Header[headerIfMatch]; ok {
+		t.Errorf("autorest: WithIfMatch added a header for an empty ETag")
+	}
+	etag = "v2"
+	r, _ = p.Prepare(mocks.NewRequest())
+	if r.Header.Get(headerIfMatch) != `"v2"` {
+		t.Errorf("autorest: WithIfMatch failed to use the ETag current when the request was prepared -- received %s", r.Header.Get(headerIfMatch))
+	}
+}
+
 func TestWithUserAgent(t *testing.T) {
 	r, err := Prepare(mocks.NewRequest(), WithUserAgent("User Agent Go"))
 	if err != nil {
@@ -494,3 +529,38 @@ func TestWithAuthorizer(t *testing.T) {
 		t.Errorf("autorest: NullAuthorizer#WithAuthorization modified the request -- received %v, expected %v", r2, r1)
 	}
 }
+
+func TestWithRequestContentHash(t *testing.T) {
+	var hash string
+	r, err := Prepare(mocks.NewRequestWithContent(jsonT), WithRequestContentHash(HashSHA256, &hash))
+	if err != nil {
+		t.Fatalf("autorest: WithRequestContentHash failed (%v)", err)
+	}
+	sum := sha256.Sum256([]byte(jsonT))
+	if expected := base64.StdEncoding.EncodeToString(sum[:]); hash != expected || r.Header.Get(headerContentSHA256) != expected {
+		t.Errorf("autorest: WithRequestContentHash set %s=%s -- expected %s", headerContentSHA256, r.Header.Get(headerContentSHA256), expected)
+	}
+	if b, _ := ioutil.ReadAll(r.Body); string(b) != jsonT {
+		t.Errorf("autorest: WithRequestContentHash failed to restore the request body -- received %q", b)
+	}
+}
+
+func TestWithRequestContentHashIgnoresEmptyBody(t *testing.T) {
+	var hash string
+	r := mocks.NewRequest()
+	r.Body = nil
+	r, err := Prepare(r, WithRequestContentHash(HashSHA256, &hash))
+	if err != nil {
+		t.Fatalf("autorest: WithRequestContentHash failed (%v)", err)
+	}
+	if _, ok := r.Header[headerContentSHA256]; ok || hash != "" {
+		t.Errorf("autorest: WithRequestContentHash hashed a request without a body")
+	}
+}
+
+func TestWithRequestContentHashReturnsErrorForUnsupportedAlgorithm(t *testing.T) {
+	var hash string
+	if _, err := Prepare(mocks.NewRequestWithContent(jsonT), WithRequestContentHash("SHA1", &hash)); err == nil {
+		t.Error("autorest: WithRequestContentHash failed to return an error for an unsupported algorithm")
+	}
+}
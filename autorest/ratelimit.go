@@ -0,0 +1,51 @@
+package autorest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is the interface that wraps the Wait method.
+//
+// Wait blocks until the limiter permits an event or the passed context.Context is done, in which
+// case it returns the error from the context. The golang.org/x/time/rate Limiter conforms to this
+// interface.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// intervalLimiter is a RateLimiter that permits one event per interval.
+type intervalLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter returns a RateLimiter, safe for concurrent use, that permits up to rps events per
+// second, spaced evenly, as measured by DefaultClock. A non-positive rps permits all events without
+// delay.
+func NewRateLimiter(rps float64) RateLimiter {
+	il := &intervalLimiter{}
+	if rps > 0 {
+		il.interval = time.Duration(float64(time.Second) / rps)
+	}
+	return il
+}
+
+// Wait implements the RateLimiter interface on intervalLimiter.
+func (il *intervalLimiter) Wait(ctx context.Context) error {
+	il.mu.Lock()
+	now := DefaultClock.Now()
+	if il.next.Before(now) {
+		il.next = now
+	}
+	delay := il.next.Sub(now)
+	il.next = il.next.Add(il.interval)
+	il.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	return sleepWithContext(ctx, delay)
+}
@@ -0,0 +1,55 @@
+package autorest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSpacesEvents(t *testing.T) {
+	l := NewRateLimiter(100)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		l.Wait(context.Background())
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("autorest: RateLimiter failed to space events -- three events took %v", d)
+	}
+}
+
+func TestRateLimiterPermitsAllEventsForNonPositiveRate(t *testing.T) {
+	l := NewRateLimiter(0)
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		l.Wait(context.Background())
+	}
+	if d := time.Since(start); d > 10*time.Millisecond {
+		t.Errorf("autorest: RateLimiter delayed events for a non-positive rate -- received %v", d)
+	}
+}
+
+func TestRateLimiterReturnsContextError(t *testing.T) {
+	l := NewRateLimiter(0.001)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Errorf("autorest: RateLimiter failed to return the context error -- received %v", err)
+	}
+}
+
+func TestRateLimiterUsesDefaultClock(t *testing.T) {
+	tc := useTestClock(t)
+	l := NewRateLimiter(2)
+
+	for i := 0; i < 3; i++ {
+		l.Wait(context.Background())
+	}
+	if !reflect.DeepEqual(tc.Sleeps(), []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}) {
+		t.Errorf("autorest: RateLimiter slept %v -- expected [500ms 500ms]", tc.Sleeps())
+	}
+}
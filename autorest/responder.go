@@ -1,11 +1,90 @@
 package autorest
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMaxInspectionBytes is the default maximum number of response body bytes copied by
+	// ByInspectingResponse.
+	DefaultMaxInspectionBytes = 1 << 20
+
+	// HashMD5 names the MD5 algorithm for ByHashingResponseBody.
+	HashMD5 = "MD5"
+
+	// HashSHA256 names the SHA-256 algorithm for ByHashingResponseBody.
+	HashSHA256 = "SHA256"
+
+	// HashCRC64 names, for ByHashingResponseBody, the CRC-64 algorithm used by Azure Storage (see
+	// CRC64Polynomial).
+	HashCRC64 = "CRC64"
+
+	// CRC64Polynomial is the CRC-64 polynomial Azure Storage uses for x-ms-content-crc64.
+	CRC64Polynomial = 0x9A6C9329AC4BC9B5
+
+	// DefaultAuditBodyBytes is the maximum number of response body bytes included in entries written
+	// by ByAuditingResponse.
+	DefaultAuditBodyBytes = 1024
+
+	// DefaultDebugBodyBytes is the maximum number of response body bytes logged by
+	// BySelectiveDebugging.
+	DefaultDebugBodyBytes = 4096
+
+	// DefaultRetryBackoff is the default initial delay between retries made by
+	// ByRetryingOnStatusCode and ByWaitingForEventualConsistency.
+	DefaultRetryBackoff = time.Second
+
+	// MaxRetryBackoff is the limit of the exponentially growing delay between retries made by
+	// ByRetryingOnStatusCode and ByWaitingForEventualConsistency. Initial delays exceeding it are
+	// not shortened, but neither do they grow.
+	MaxRetryBackoff = 5 * time.Minute
+)
+
+var (
+	// ErrResponseBodyTooLarge is the error returned when reading a response body beyond the limit
+	// set by ByLimitingBodySize.
+	ErrResponseBodyTooLarge = errors.New("autorest: response body exceeds the size limit")
+
+	// ErrResponseReadTimeout is the error returned when a read of a response body wrapped by
+	// ByTimeoutingResponse does not complete in time.
+	ErrResponseReadTimeout = errors.New("autorest: response body read timed out")
+
+	// ErrResponseSignatureMismatch is the original error of the Error returned by
+	// ByVerifyingResponseSignature when the response signature does not match.
+	ErrResponseSignatureMismatch = errors.New("autorest: response signature does not match")
+
+	// ErrCircuitOpen is the original error of the Error returned by ByCircuitBreaker while the
+	// circuit is open.
+	ErrCircuitOpen = errors.New("autorest: circuit breaker is open")
 )
 
 // Responder is the interface that wraps the Respond method.
@@ -24,6 +103,41 @@ func (rf ResponderFunc) Respond(r *http.Response) error {
 	return rf(r)
 }
 
+// NoopResponder is a Responder that does nothing and returns nil. Being comparable, it may be used
+// as a sentinel (e.g., if r == NoopResponder).
+var NoopResponder Responder = noopResponder{}
+
+type noopResponder struct{}
+
+// Respond implements the Responder interface on noopResponder.
+func (nr noopResponder) Respond(r *http.Response) error {
+	return nil
+}
+
+// closingResponderFunc is a ResponderFunc that closes the response body. ByClosing and similar
+// decorators return it so that CreateSafeResponder can detect them.
+type closingResponderFunc func(*http.Response) error
+
+// Respond implements the Responder interface on closingResponderFunc.
+func (crf closingResponderFunc) Respond(r *http.Response) error {
+	return crf(r)
+}
+
+func (crf closingResponderFunc) closesBody() {}
+
+// bodyClosingResponder is the marker interface of Responders that close the response body.
+type bodyClosingResponder interface {
+	closesBody()
+}
+
+// Logger is the interface that wraps the Log method.
+//
+// Log accepts alternating keys and values describing a single event. It is compatible with the
+// go-kit/log Logger and similar structured loggers.
+type Logger interface {
+	Log(keyvals ...interface{}) error
+}
+
 // RespondDecorator takes and possibly decorates, by wrapping, a Responder. Decorators may react to
 // the http.Response and pass it along or, first, pass the http.Response along then react.
 type RespondDecorator func(Responder) Responder
@@ -34,13 +148,95 @@ type RespondDecorator func(Responder) Responder
 // the response body is fine to share whereas a decorator that reads the body into a passed struct
 // is not.
 //
-// To prevent memory leaks, ensure that at least one Responder closes the response body.
+// To prevent memory leaks, ensure that at least one Responder closes the response body (e.g., by
+// applying ByClosing) or use CreateSafeResponder, which does so automatically.
 func CreateResponder(decorators ...RespondDecorator) Responder {
 	return DecorateResponder(
-		Responder(ResponderFunc(func(r *http.Response) error { return nil })),
+		NoopResponder,
 		decorators...)
 }
 
+// CreateSafeResponder creates, decorates, and returns a Responder that, like CreateResponder, applies
+// the passed decorators but, unless one of them is ByClosing, ByClosingWithError, ByClosingOnce, or
+// ByClosingIfError, also applies ByClosing as the final decorator so that the response body is
+// always closed.
+func CreateSafeResponder(decorators ...RespondDecorator) Responder {
+	for _, decorate := range decorators {
+		if _, ok := decorate(NoopResponder).(bodyClosingResponder); ok {
+			return CreateResponder(decorators...)
+		}
+	}
+	return CreateResponder(append(decorators[:len(decorators):len(decorators)], ByClosing())...)
+}
+
+// CreateResponderWithContext creates, decorates, and returns a Responder that, before invoking any
+// decorator, sets the passed context.Context on the http.Request of the http.Response. Decorators
+// may then consult the context by means of resp.Request.Context(). Responses lacking a request are
+// passed along unmodified.
+func CreateResponderWithContext(ctx context.Context, decorators ...RespondDecorator) Responder {
+	r := CreateResponder(decorators...)
+	return ResponderFunc(func(resp *http.Response) error {
+		if resp != nil && resp.Request != nil {
+			resp.Request = resp.Request.WithContext(ctx)
+		}
+		return r.Respond(resp)
+	})
+}
+
+// ResponderOption configures the environment in which a Responder runs. Options derive, from the
+// context.Context of the response's http.Request, the context available to all decorators by
+// means of resp.Request.Context().
+type ResponderOption func(context.Context) context.Context
+
+// WithContext returns a ResponderOption that replaces the context with the passed context.Context.
+// Since it discards values set by prior options, it should precede all other options.
+func WithContext(ctx context.Context) ResponderOption {
+	return func(context.Context) context.Context {
+		return ctx
+	}
+}
+
+// WithLogger returns a ResponderOption that makes the passed Logger available to decorators by
+// means of LoggerFromContext.
+func WithLogger(logger Logger) ResponderOption {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, ContextLoggerKey, logger)
+	}
+}
+
+// WithRequestID returns a ResponderOption that makes the passed request ID available to decorators
+// by means of RequestIDFromContext.
+func WithRequestID(id string) ResponderOption {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, ContextRequestIDKey, id)
+	}
+}
+
+// WithCorrelationID returns a ResponderOption that makes the passed correlation ID available to
+// decorators by means of CorrelationIDFromContext.
+func WithCorrelationID(id string) ResponderOption {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, ContextCorrelationIDKey, id)
+	}
+}
+
+// CreateResponderWithOptions creates, decorates, and returns a Responder that, before invoking any
+// decorator, applies the passed ResponderOptions, in order, to the context of the response's
+// http.Request. Responses lacking a request are passed along unmodified.
+func CreateResponderWithOptions(options []ResponderOption, decorators ...RespondDecorator) Responder {
+	r := CreateResponder(decorators...)
+	return ResponderFunc(func(resp *http.Response) error {
+		if resp != nil && resp.Request != nil {
+			ctx := resp.Request.Context()
+			for _, option := range options {
+				ctx = option(ctx)
+			}
+			resp.Request = resp.Request.WithContext(ctx)
+		}
+		return r.Respond(resp)
+	})
+}
+
 // DecorateResponder accepts a Responder and a, possibly empty, set of RespondDecorators, which it
 // applies to the Responder. Decorators are applied in the order received, but their affect upon the
 // request depends on whether they are a pre-decorator (react to the http.Response and then pass it
@@ -52,6 +248,94 @@ func DecorateResponder(r Responder, decorators ...RespondDecorator) Responder {
 	return r
 }
 
+// CombineResponders returns a Responder that concurrently passes the http.Response to each of the
+// passed Responders. Each Responder receives its own shallow copy of the http.Response whose body
+// independently reads the original content; the body is read once and then restored, with its
+// original Closer, on the passed http.Response. If any Responder fails, the returned Error
+// aggregates all failures as its original error. Since the copies share headers and other
+// reference fields, the Responders must not modify them.
+func CombineResponders(responders ...Responder) Responder {
+	return ResponderFunc(func(resp *http.Response) error {
+		if resp == nil {
+			return nil
+		}
+
+		var b []byte
+		if resp.Body != nil {
+			var err error
+			b, err = ioutil.ReadAll(resp.Body)
+			resp.Body = readCloser{Reader: bytes.NewReader(b), Closer: resp.Body}
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "CombineResponders", resp.StatusCode, "Failure reading response body")
+			}
+		}
+
+		errs := make([]error, len(responders))
+		var wg sync.WaitGroup
+		for i, r := range responders {
+			c := *resp
+			if resp.Body != nil {
+				c.Body = ioutil.NopCloser(bytes.NewReader(b))
+			}
+			wg.Add(1)
+			go func(i int, r Responder, c *http.Response) {
+				defer wg.Done()
+				errs[i] = r.Respond(c)
+			}(i, r, &c)
+		}
+		wg.Wait()
+
+		var failed []error
+		for _, err := range errs {
+			if err != nil {
+				failed = append(failed, err)
+			}
+		}
+		if len(failed) > 0 {
+			return NewErrorWithError(errors.Join(failed...), "autorest", "CombineResponders", resp.StatusCode, "%d of %d Responders failed",
+				len(failed),
+				len(responders))
+		}
+		return nil
+	})
+}
+
+// ByGroupingErrors returns a RespondDecorator that, after invoking the passed Responder, splits a
+// batch response into its sub-responses using subResponseExtractor and checks each of them. Every
+// sub-response whose StatusCode is not 2xx yields a *DetailedError (see
+// WithDetailedErrorUnlessStatusCode); if any do, the decorator returns a single error whose original
+// error joins them all (see errors.Join). It returns an error if the extractor fails.
+func ByGroupingErrors(subResponseExtractor func(*http.Response) ([]*http.Response, error)) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || resp == nil {
+				return err
+			}
+			subs, err := subResponseExtractor(resp)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByGroupingErrors", resp.StatusCode, "Failure extracting sub-responses")
+			}
+
+			var failed []error
+			for i, sub := range subs {
+				if sub == nil || (sub.StatusCode >= http.StatusOK && sub.StatusCode < http.StatusMultipleChoices) {
+					continue
+				}
+				failed = append(failed, NewDetailedError(extractServiceError(sub), "autorest", "ByGroupingErrors", sub.StatusCode, "Sub-response %d failed with %s",
+					i,
+					sub.Status))
+			}
+			if len(failed) > 0 {
+				return NewErrorWithError(errors.Join(failed...), "autorest", "ByGroupingErrors", resp.StatusCode, "%d of %d sub-responses failed",
+					len(failed),
+					len(subs))
+			}
+			return nil
+		})
+	}
+}
+
 // Respond accepts an http.Response and a, possibly empty, set of RespondDecorators.
 // It creates a Responder from the decorators it then applies to the passed http.Response.
 func Respond(r *http.Response, decorators ...RespondDecorator) error {
@@ -61,6 +345,195 @@ func Respond(r *http.Response, decorators ...RespondDecorator) error {
 	return CreateResponder(decorators...).Respond(r)
 }
 
+// RespondAll concurrently applies the passed Responder to each of the passed http.Responses and
+// returns the resulting errors, nil for those that succeeded, in the order of the responses. As with
+// Respond, nil responses are skipped. The Responder must be safe for concurrent use.
+func RespondAll(r Responder, responses []*http.Response) []error {
+	errs := make([]error, len(responses))
+	var wg sync.WaitGroup
+	for i, resp := range responses {
+		if resp == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, resp *http.Response) {
+			defer wg.Done()
+			errs[i] = r.Respond(resp)
+		}(i, resp)
+	}
+	wg.Wait()
+	return errs
+}
+
+// RespondAllSerial behaves like RespondAll but applies the Responder to the responses one at a
+// time, in order, which makes the processing deterministic (e.g., for tests).
+func RespondAllSerial(r Responder, responses []*http.Response) []error {
+	errs := make([]error, len(responses))
+	for i, resp := range responses {
+		if resp != nil {
+			errs[i] = r.Respond(resp)
+		}
+	}
+	return errs
+}
+
+// DecorateResponderFromSlice behaves like DecorateResponder but accepts the RespondDecorators as a
+// slice, which is convenient when the set of decorators is built programmatically.
+func DecorateResponderFromSlice(r Responder, decorators []RespondDecorator) Responder {
+	return DecorateResponder(r, decorators...)
+}
+
+// ComposeRespondDecorators returns a single slice holding the RespondDecorators of the passed
+// groups, in order. Nil or empty groups, as well as nil decorators, are skipped. The returned slice
+// is newly allocated, so appending to it never modifies the passed groups.
+func ComposeRespondDecorators(groups ...[]RespondDecorator) []RespondDecorator {
+	n := 0
+	for _, g := range groups {
+		n += len(g)
+	}
+	decorators := make([]RespondDecorator, 0, n)
+	for _, g := range groups {
+		for _, d := range g {
+			if d != nil {
+				decorators = append(decorators, d)
+			}
+		}
+	}
+	return decorators
+}
+
+// NamedDecorator returns a RespondDecorator that behaves like d but carries the passed name, which
+// DecoratorName reports. Errors arising in d, rather than in the Responder d decorates, are wrapped
+// as "decorator <name>: <error>" (see fmt.Errorf) so that they identify the failing decorator;
+// errors of the decorated Responder pass through unchanged. Naming a decorator that closes the
+// response body (e.g., ByClosing) does not hide it from CreateSafeResponder.
+func NamedDecorator(name string, d RespondDecorator) RespondDecorator {
+	return func(r Responder) Responder {
+		inner := d(ResponderFunc(func(resp *http.Response) error {
+			if err := r.Respond(resp); err != nil {
+				return passedError{err}
+			}
+			return nil
+		}))
+		nr := namedResponder{name: name, r: inner}
+		if _, ok := inner.(bodyClosingResponder); ok {
+			return namedClosingResponder{nr}
+		}
+		return nr
+	}
+}
+
+// DecoratorName returns the name given to the passed RespondDecorator by NamedDecorator, or the
+// empty string if it has none.
+func DecoratorName(d RespondDecorator) string {
+	switch nr := d(NoopResponder).(type) {
+	case namedResponder:
+		return nr.name
+	case namedClosingResponder:
+		return nr.name
+	}
+	return ""
+}
+
+// namedResponder is the Responder returned by the RespondDecorators NamedDecorator creates.
+type namedResponder struct {
+	name string
+	r    Responder
+}
+
+// Respond implements the Responder interface on namedResponder.
+func (nr namedResponder) Respond(resp *http.Response) error {
+	err := nr.r.Respond(resp)
+	if pe, ok := err.(passedError); ok {
+		return pe.err
+	} else if err != nil {
+		return fmt.Errorf("decorator %s: %w", nr.name, err)
+	}
+	return nil
+}
+
+// namedClosingResponder is a namedResponder whose decorator closes the response body.
+type namedClosingResponder struct {
+	namedResponder
+}
+
+func (ncr namedClosingResponder) closesBody() {}
+
+// passedError marks an error returned by the Responder decorated by a NamedDecorator so that the
+// error is not attributed to the named decorator.
+type passedError struct {
+	err error
+}
+
+func (pe passedError) Error() string {
+	return pe.err.Error()
+}
+
+func (pe passedError) Unwrap() error {
+	return pe.err
+}
+
+// ResponderBuilder incrementally collects RespondDecorators from which it builds a Responder.
+// Decorators are applied in the order added. A ResponderBuilder is not safe for concurrent use.
+type ResponderBuilder struct {
+	decorators []RespondDecorator
+}
+
+// NewResponderBuilder returns a ResponderBuilder initialized with the passed, possibly empty, set
+// of RespondDecorators.
+func NewResponderBuilder(base ...RespondDecorator) *ResponderBuilder {
+	return &ResponderBuilder{decorators: append([]RespondDecorator(nil), base...)}
+}
+
+// Add appends the passed RespondDecorator and returns the ResponderBuilder to allow chaining.
+func (rb *ResponderBuilder) Add(d RespondDecorator) *ResponderBuilder {
+	rb.decorators = append(rb.decorators, d)
+	return rb
+}
+
+// Build creates, decorates, and returns a Responder from the RespondDecorators added so far (see
+// CreateResponder). Subsequent additions do not affect previously built Responders.
+func (rb *ResponderBuilder) Build() Responder {
+	return CreateResponder(rb.decorators...)
+}
+
+// RespondWithContext behaves like Respond but checks, before invoking each decorator, whether the
+// passed context.Context is done. If it is, it stops processing the http.Response and returns the
+// error from the context.
+func RespondWithContext(ctx context.Context, r *http.Response, decorators ...RespondDecorator) error {
+	if r == nil {
+		return nil
+	}
+	var rr Responder = withContextCheck(ctx, NoopResponder)
+	for _, decorate := range decorators {
+		rr = withContextCheck(ctx, decorate(rr))
+	}
+	return rr.Respond(r)
+}
+
+// withContextCheck wraps the passed Responder so that it returns the error of the passed
+// context.Context, without invoking the Responder, once the context is done.
+func withContextCheck(ctx context.Context, r Responder) Responder {
+	return ResponderFunc(func(resp *http.Response) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			return r.Respond(resp)
+		}
+	})
+}
+
+// RespondWithOptions accepts an http.Response, a set of ResponderOptions, and a, possibly empty, set
+// of RespondDecorators. It creates a Responder from the options and decorators (see
+// CreateResponderWithOptions) which it then applies to the passed http.Response.
+func RespondWithOptions(r *http.Response, options []ResponderOption, decorators ...RespondDecorator) error {
+	if r == nil {
+		return nil
+	}
+	return CreateResponderWithOptions(options, decorators...).Respond(r)
+}
+
 // ByIgnoring returns a RespondDecorator that ignores the passed http.Response passing it unexamined
 // to the next RespondDecorator.
 func ByIgnoring() RespondDecorator {
@@ -71,12 +544,106 @@ func ByIgnoring() RespondDecorator {
 	}
 }
 
+// BySkippingOnCondition returns a RespondDecorator that short-circuits the chain: If pred returns
+// true for the http.Response, it returns nil without invoking the passed Responder, so none of the
+// decorators preceding it in the list (e.g., ByUnmarshallingJSON for an HTTP 204 No Content response)
+// run. Decorators following it in the list, such as ByClosing, still run. Unlike error suppression,
+// the skipped decorators never see the response at all.
+func BySkippingOnCondition(pred func(*http.Response) bool) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if pred(resp) {
+				return nil
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByInspectingResponse returns a RespondDecorator that passes the http.Response and a copy of
+// (up to DefaultMaxInspectionBytes of) its body to the supplied function before invoking the passed
+// Responder. The body is restored so that subsequent decorators see it unmodified.
+func ByInspectingResponse(fn func(*http.Response, []byte)) RespondDecorator {
+	return ByInspectingResponseWithLimit(fn, DefaultMaxInspectionBytes)
+}
+
+// ByInspectingResponseWithLimit returns a RespondDecorator that behaves like ByInspectingResponse
+// but copies at most maxBytes of the body. Bodies larger than maxBytes are truncated in the copy
+// passed to the function, but not in the restored body.
+func ByInspectingResponseWithLimit(fn func(*http.Response, []byte), maxBytes int64) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil {
+				var b []byte
+				if resp.Body != nil {
+					var err error
+					b, err = peekBody(resp, maxBytes)
+					if err != nil {
+						return NewErrorWithError(err, "autorest", "ByInspectingResponse", resp.StatusCode, "Failure reading response body")
+					}
+				}
+				fn(resp, b)
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
 // ByClosing returns a RespondDecorator that first invokes the passed Responder after which it
 // closes the response body. Since the passed Responder is invoked prior to closing the response
 // body, the decorator may occur anywhere within the set.
 func ByClosing() RespondDecorator {
 	return func(r Responder) Responder {
-		return ResponderFunc(func(resp *http.Response) error {
+		return closingResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if resp != nil && resp.Body != nil {
+				resp.Body.Close()
+			}
+			return err
+		})
+	}
+}
+
+// ByClosingWithError returns a RespondDecorator that, like ByClosing, first invokes the passed
+// Responder after which it closes the response body. Unlike ByClosing, it does not discard errors
+// returned by closing the body: If only closing fails, it returns that error; if both the passed
+// Responder and closing fail, it returns an error carrying the close failure in its message and the
+// Responder error as the original error.
+func ByClosingWithError() RespondDecorator {
+	return func(r Responder) Responder {
+		return closingResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if resp != nil && resp.Body != nil {
+				if cerr := resp.Body.Close(); cerr != nil {
+					if err == nil {
+						return NewErrorWithError(cerr, "autorest", "ByClosingWithError", resp.StatusCode, "Failure closing response body")
+					}
+					return baseError{
+						packageType: "autorest",
+						method:      "ByClosingWithError",
+						statusCode:  resp.StatusCode,
+						message:     fmt.Sprintf("Failure closing response body (%v)", cerr),
+						original:    err,
+					}
+				}
+			}
+			return err
+		})
+	}
+}
+
+// ByClosingOnce returns a RespondDecorator that, like ByClosing, first invokes the passed Responder
+// after which it closes the response body. It first replaces the body with one whose Close, backed
+// by a sync.Once, closes the original body only on the first call, so that subsequent ByClosingOnce,
+// ByClosing, or similar decorators in the chain do not close it again.
+func ByClosingOnce() RespondDecorator {
+	return func(r Responder) Responder {
+		return closingResponderFunc(func(resp *http.Response) error {
+			if resp != nil && resp.Body != nil {
+				if _, ok := resp.Body.(*onceCloser); !ok {
+					resp.Body = &onceCloser{ReadCloser: resp.Body}
+				}
+			}
 			err := r.Respond(resp)
 			if resp != nil && resp.Body != nil {
 				resp.Body.Close()
@@ -88,9 +655,12 @@ func ByClosing() RespondDecorator {
 
 // ByClosingIfError returns a RespondDecorator that first invokes the passed Responder after which
 // it closes the response if the passed Responder returns an error and the response body exists.
+// Only the returned error decides; this includes context.Canceled and context.DeadlineExceeded,
+// however deeply an inner decorator wraps them. Successful responses are left open even if the
+// context of their http.Request is done.
 func ByClosingIfError() RespondDecorator {
 	return func(r Responder) Responder {
-		return ResponderFunc(func(resp *http.Response) error {
+		return closingResponderFunc(func(resp *http.Response) error {
 			err := r.Respond(resp)
 			if err != nil && resp != nil && resp.Body != nil {
 				resp.Body.Close()
@@ -100,64 +670,2439 @@ func ByClosingIfError() RespondDecorator {
 	}
 }
 
-// ByUnmarshallingJSON returns a RespondDecorator that decodes a JSON document returned in the
-// response Body into the value pointed to by v.
-func ByUnmarshallingJSON(v interface{}) RespondDecorator {
+// ByExtractingHeader returns a RespondDecorator that first invokes the passed Responder after which
+// it copies the first value of the specified header into the string pointed to by dest. dest is set
+// to the empty string if the header does not exist.
+func ByExtractingHeader(header string, dest *string) RespondDecorator {
 	return func(r Responder) Responder {
 		return ResponderFunc(func(resp *http.Response) error {
 			err := r.Respond(resp)
-			if err == nil {
-				b := bytes.Buffer{}
-				d := json.NewDecoder(io.TeeReader(resp.Body, &b))
-				err = d.Decode(v)
-				if err != nil {
-					err = fmt.Errorf("Error (%v) occurred decoding JSON (\"%s\")", err, b.String())
-				}
-			}
+			*dest = ExtractHeaderValue(header, resp)
 			return err
 		})
 	}
 }
 
-// WithErrorUnlessStatusCode returns a RespondDecorator that emits an error unless the response
-// StatusCode is among the set passed. Since these are artificial errors, the response body
-// may still require closing.
-func WithErrorUnlessStatusCode(codes ...int) RespondDecorator {
+// ByCapturingETag returns a RespondDecorator that first invokes the passed Responder after which
+// it copies the ETag header, unquoted if quoted, into the string pointed to by dest (e.g., for use
+// by WithIfMatch in a subsequent PUT or PATCH). Weak ETags (e.g., W/"v") are copied as is. dest is
+// set to the empty string if the header does not exist.
+func ByCapturingETag(dest *string) RespondDecorator {
 	return func(r Responder) Responder {
 		return ResponderFunc(func(resp *http.Response) error {
 			err := r.Respond(resp)
-			if err == nil && !ResponseHasStatusCode(resp, codes...) {
-				err = NewErrorWithStatusCode("autorest", "WithErrorUnlessStatusCode", resp.StatusCode, "%v %v failed with %s",
-					resp.Request.Method,
-					resp.Request.URL,
-					resp.Status)
+			etag := ExtractHeaderValue(headerETag, resp)
+			if len(etag) >= 2 && strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`) {
+				etag = etag[1 : len(etag)-1]
 			}
+			*dest = etag
 			return err
 		})
 	}
 }
 
-// WithErrorUnlessOK returns a RespondDecorator that emits an error if the response StatusCode is
-// anything other than HTTP 200.
-func WithErrorUnlessOK() RespondDecorator {
-	return WithErrorUnlessStatusCode(http.StatusOK)
+// ByParsingLinkHeader returns a RespondDecorator that first invokes the passed Responder after which
+// it parses the Link headers of the response (see RFC 5988) and copies into the string pointed to
+// by dest the target URL of the first link whose relation types include rel (e.g., "next").
+// Relation types are matched without regard to case. dest is set to the empty string if no such
+// link exists.
+func ByParsingLinkHeader(rel string, dest *string) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			*dest = findLink(ExtractHeader(headerLink, resp), rel)
+			return err
+		})
+	}
 }
 
-// ExtractHeader extracts all values of the specified header from the http.Response. It returns an
-// empty string slice if the passed http.Response is nil or the header does not exist.
-func ExtractHeader(header string, resp *http.Response) []string {
-	if resp != nil && resp.Header != nil {
-		return resp.Header[http.CanonicalHeaderKey(header)]
+// ByNormalizingHeaders returns a RespondDecorator that, before invoking the passed Responder,
+// re-keys all response headers by their canonical name (see http.CanonicalHeaderKey) so that
+// subsequent decorators may index resp.Header directly. Values of headers merged under the same
+// canonical name are kept in order, less any values repeated by the merge.
+func ByNormalizingHeaders() RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil && resp.Header != nil {
+				keys := make([]string, 0, len(resp.Header))
+				for k := range resp.Header {
+					keys = append(keys, k)
+				}
+				// Visit canonical keys first so that their values precede those merged into them
+				sort.Slice(keys, func(i, j int) bool {
+					ci, cj := keys[i] == http.CanonicalHeaderKey(keys[i]), keys[j] == http.CanonicalHeaderKey(keys[j])
+					if ci != cj {
+						return ci
+					}
+					return keys[i] < keys[j]
+				})
+
+				h := make(http.Header, len(resp.Header))
+				for _, k := range keys {
+					ck := http.CanonicalHeaderKey(k)
+					if _, merged := h[ck]; !merged {
+						h[ck] = append([]string(nil), resp.Header[k]...)
+						continue
+					}
+					for _, v := range resp.Header[k] {
+						if !containsString(h[ck], v) {
+							h[ck] = append(h[ck], v)
+						}
+					}
+				}
+				resp.Header = h
+			}
+			return r.Respond(resp)
+		})
 	}
-	return nil
 }
 
-// ExtractHeaderValue extracts the first value of the specified header from the http.Response. It
-// returns an empty string if the passed http.Response is nil or the header does not exist.
-func ExtractHeaderValue(header string, resp *http.Response) string {
-	h := ExtractHeader(header, resp)
-	if len(h) > 0 {
-		return h[0]
+// ByAddingResponseHeader returns a RespondDecorator that, before invoking the passed Responder,
+// sets the response header key to value. The value may refer to the x-ms-request-id and
+// x-ms-correlation-request-id response headers by means of the {requestId} and {correlationId}
+// placeholders, which are replaced by the values of those headers (or the empty string if absent).
+func ByAddingResponseHeader(key, value string) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil {
+				if resp.Header == nil {
+					resp.Header = http.Header{}
+				}
+				v := strings.NewReplacer(
+					"{requestId}", resp.Header.Get(headerRequestID),
+					"{correlationId}", resp.Header.Get(headerCorrelationRequestID)).Replace(value)
+				resp.Header.Set(key, v)
+			}
+			return r.Respond(resp)
+		})
 	}
-	return ""
+}
+
+// BySettingRequestIDFromResponse returns a RespondDecorator that, before invoking the passed
+// Responder, stores the values of the x-ms-request-id and x-ms-correlation-request-id response
+// headers, if present, in the context of the response's http.Request under ContextRequestIDKey and
+// ContextCorrelationIDKey. Decorators, such as loggers, may then retrieve them by means of
+// RequestIDFromContext and CorrelationIDFromContext.
+func BySettingRequestIDFromResponse() RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil && resp.Request != nil {
+				ctx := resp.Request.Context()
+				if id := resp.Header.Get(headerRequestID); id != "" {
+					ctx = context.WithValue(ctx, ContextRequestIDKey, id)
+				}
+				if id := resp.Header.Get(headerCorrelationRequestID); id != "" {
+					ctx = context.WithValue(ctx, ContextCorrelationIDKey, id)
+				}
+				if ctx != resp.Request.Context() {
+					resp.Request = resp.Request.WithContext(ctx)
+				}
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByEnforcingRequiredHeaders returns a RespondDecorator that, before invoking the passed
+// Responder, verifies that every one of the passed headers is present, with a non-empty value, in
+// the response. It returns an Error naming all missing headers, without invoking the passed
+// Responder, if any are absent or empty, so that responses violating the API contract are rejected
+// before their body is processed. Since the error is artificial, the response body may still require
+// closing.
+func ByEnforcingRequiredHeaders(headers ...string) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil {
+				return r.Respond(resp)
+			}
+			var missing []string
+			for _, h := range headers {
+				if resp.Header.Get(h) == "" {
+					missing = append(missing, h)
+				}
+			}
+			if len(missing) > 0 {
+				return NewErrorWithStatusCode("autorest", "ByEnforcingRequiredHeaders", resp.StatusCode, "Response is missing required headers %s",
+					strings.Join(missing, ", "))
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByAddingRequestID returns a RespondDecorator that, before invoking the passed Responder, ensures
+// the response carries an x-ms-client-request-id header. If the service did not return one, it
+// sets the header to the value sent with the request or, if the request carried none either, to a
+// newly generated random UUID. The ID is also stored in the context of the response's http.Request
+// under ContextClientRequestIDKey (see ClientRequestIDFromContext).
+func ByAddingRequestID() RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil {
+				return r.Respond(resp)
+			}
+			id := resp.Header.Get(headerClientRequestID)
+			if id == "" && resp.Request != nil {
+				id = resp.Request.Header.Get(headerClientRequestID)
+			}
+			if id == "" {
+				var err error
+				if id, err = newUUID(); err != nil {
+					return NewErrorWithError(err, "autorest", "ByAddingRequestID", resp.StatusCode, "Failure generating request ID")
+				}
+			}
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			resp.Header.Set(headerClientRequestID, id)
+			if resp.Request != nil {
+				resp.Request = resp.Request.WithContext(context.WithValue(resp.Request.Context(), ContextClientRequestIDKey, id))
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByVerifyingTLSCertificate returns a RespondDecorator that, before invoking the passed Responder,
+// verifies that the SHA-256 fingerprint of the leaf certificate presented by the server (i.e., the
+// first of the response's TLS.PeerCertificates) equals expectedSHA256. It returns an error, without
+// invoking the passed Responder, if the fingerprint does not match or the response was not received
+// over TLS, allowing the response to be rejected before its body is processed.
+func ByVerifyingTLSCertificate(expectedSHA256 []byte) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+				return NewError("autorest", "ByVerifyingTLSCertificate", "Response lacks a TLS peer certificate")
+			}
+			fingerprint := sha256.Sum256(resp.TLS.PeerCertificates[0].Raw)
+			if subtle.ConstantTimeCompare(fingerprint[:], expectedSHA256) != 1 {
+				return NewErrorWithStatusCode("autorest", "ByVerifyingTLSCertificate", resp.StatusCode, "TLS peer certificate fingerprint %x does not match the expected %x", fingerprint, expectedSHA256)
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByDecompressingBody returns a RespondDecorator that, before invoking the passed Responder,
+// replaces a response body compressed per the Content-Encoding header (gzip, x-gzip, deflate, or
+// identity) with one that reads the decompressed content. A body compressed by several encodings
+// (e.g., "gzip, deflate") is decompressed in the reverse order of their application. It then
+// removes the Content-Encoding header and sets ContentLength to -1 (i.e., unknown). It emits an
+// error for unsupported encodings.
+func ByDecompressingBody() RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil && resp.Body != nil && resp.Header.Get(headerContentEncoding) != "" {
+				var encodings []string
+				for _, v := range resp.Header.Values(headerContentEncoding) {
+					for _, e := range strings.Split(v, ",") {
+						encodings = append(encodings, strings.ToLower(strings.TrimSpace(e)))
+					}
+				}
+
+				rc := resp.Body
+				for i := len(encodings) - 1; i >= 0; i-- {
+					var d io.ReadCloser
+					var err error
+					switch encodings[i] {
+					case "", "identity":
+						continue
+					case "gzip", "x-gzip":
+						d, err = gzip.NewReader(rc)
+					case "deflate":
+						d, err = zlib.NewReader(rc)
+					default:
+						return NewErrorWithStatusCode("autorest", "ByDecompressingBody", resp.StatusCode, "Unsupported Content-Encoding %s", encodings[i])
+					}
+					if err != nil {
+						return NewErrorWithError(err, "autorest", "ByDecompressingBody", resp.StatusCode, "Failure decompressing %s response body", encodings[i])
+					}
+					rc = readCloser{Reader: d, Closer: rc}
+				}
+
+				resp.Body = rc
+				resp.Header.Del(headerContentEncoding)
+				resp.ContentLength = -1
+				resp.Uncompressed = true
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByCapturingRawResponse returns a RespondDecorator that, before invoking the passed Responder,
+// saves into the byte slice pointed to by dest the response, including its status line, headers,
+// and body, as it would appear on the wire (see httputil.DumpResponse). The body is replaced with
+// a buffered copy so that subsequent decorators see it unmodified. The captured bytes are suitable,
+// for example, for recording fixtures replayed with the testutil package.
+func ByCapturingRawResponse(dest *[]byte) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil {
+				b, err := httputil.DumpResponse(resp, true)
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByCapturingRawResponse", resp.StatusCode, "Failure capturing the raw response")
+				}
+				*dest = b
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByStoringResponse returns a RespondDecorator that saves the passed http.Response into the pointer
+// referenced by dest before invoking the passed Responder, so that callers may inspect it after the
+// Responder returns.
+func ByStoringResponse(dest **http.Response) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			*dest = resp
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByLimitingBodySize returns a RespondDecorator that limits the passed Responder to reading at most
+// maxBytes of the response body. Reads beyond the limit fail with ErrResponseBodyTooLarge and, if
+// the body was found to exceed the limit, the decorator returns an Error whose original error is
+// ErrResponseBodyTooLarge in place of any error from the passed Responder, so that truncation is
+// distinguishable from other (e.g., decoding) failures.
+func ByLimitingBodySize(maxBytes int64) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || resp.Body == nil {
+				return r.Respond(resp)
+			}
+			lb := &limitedBody{ReadCloser: resp.Body, remaining: maxBytes}
+			resp.Body = lb
+			err := r.Respond(resp)
+			if lb.exceeded {
+				err = NewErrorWithError(ErrResponseBodyTooLarge, "autorest", "ByLimitingBodySize", resp.StatusCode, "Response body exceeds %d bytes", maxBytes)
+			}
+			return err
+		})
+	}
+}
+
+// BufferedBody is a response body held entirely in memory (see ByBufferingBody). Like any
+// io.Reader, it must not be read from several goroutines at once; each goroutine should instead read
+// from its own reader obtained from NewReader.
+type BufferedBody struct {
+	*bytes.Reader
+	b []byte
+}
+
+// Bytes returns the complete content of the body. The returned slice must not be modified.
+func (bb *BufferedBody) Bytes() []byte {
+	return bb.b
+}
+
+// NewReader returns a new io.ReadCloser over the complete content of the body, independent of the
+// BufferedBody and of any other reader it returned.
+func (bb *BufferedBody) NewReader() io.ReadCloser {
+	return ioutil.NopCloser(bytes.NewReader(bb.b))
+}
+
+// Close implements the io.Closer interface; the underlying body is closed once buffered.
+func (bb *BufferedBody) Close() error {
+	return nil
+}
+
+// ByBufferingBody returns a RespondDecorator that, before invoking the passed Responder, reads the
+// response body, of at most maxSize bytes, into memory, closes it, and replaces it with a
+// *BufferedBody from which concurrent readers may be created. It returns an Error whose original
+// error is ErrResponseBodyTooLarge, without invoking the passed Responder, if the body exceeds
+// maxSize bytes.
+func ByBufferingBody(maxSize int64) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+				return r.Respond(resp)
+			}
+			b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+			resp.Body.Close()
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByBufferingBody", resp.StatusCode, "Failure reading response body")
+			}
+			if int64(len(b)) > maxSize {
+				return NewErrorWithError(ErrResponseBodyTooLarge, "autorest", "ByBufferingBody", resp.StatusCode, "Response body exceeds %d bytes", maxSize)
+			}
+			resp.Body = &BufferedBody{Reader: bytes.NewReader(b), b: b}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByDroppingBody returns a RespondDecorator that, before invoking the passed Responder, reads the
+// response body to its end, discarding the content, closes it, and replaces it with http.NoBody.
+// Since the body is fully read before being closed, the underlying connection remains eligible for
+// reuse by the http.Transport. Subsequent decorators see an empty body.
+func ByDroppingBody() RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+				return r.Respond(resp)
+			}
+			_, err := io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			resp.Body = http.NoBody
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByDroppingBody", resp.StatusCode, "Failure discarding response body")
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByComputingResponseSize returns a RespondDecorator that, before invoking the passed Responder,
+// replaces the response body with one that adds the number of bytes read from it to the int64
+// pointed to by dest. The count thus covers all content read by subsequent decorators and, if the
+// caller retains the body, that read after Respond returns. dest is updated atomically, so it may be
+// read, by means of atomic.LoadInt64, while the body is read elsewhere.
+func ByComputingResponseSize(dest *int64) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil && resp.Body != nil && resp.Body != http.NoBody {
+				resp.Body = &countingBody{Reader: resp.Body, Closer: resp.Body, count: dest}
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByForwardingBodyTo returns a RespondDecorator that, before invoking the passed Responder,
+// replaces the response body with one that, by means of a TeeReader, writes everything read from
+// it to w. The body remains readable by other decorators (e.g., ByUnmarshallingJSON); only the
+// content they read is forwarded to w.
+func ByForwardingBodyTo(w io.Writer) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil && resp.Body != nil {
+				resp.Body = readCloser{Reader: io.TeeReader(resp.Body, w), Closer: resp.Body}
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByStreamingBodyTo returns a RespondDecorator that, before invoking the passed Responder, hands
+// the response body to the caller by saving it into the io.ReadCloser pointed to by dest and
+// replacing it with http.NoBody. The remaining decorators (including ByClosing) thus proceed
+// without reading, buffering, or closing the stream; the caller must read and close *dest once
+// Respond returns.
+func ByStreamingBodyTo(dest *io.ReadCloser) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil && resp.Body != nil {
+				*dest = resp.Body
+				resp.Body = http.NoBody
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByTimeoutingResponse returns a RespondDecorator that, before invoking the passed Responder,
+// replaces the response body with one whose every Read fails with ErrResponseReadTimeout if it does
+// not complete within d. After a timeout, the original body is closed (to release the connection)
+// and all further reads fail. Unlike the http.Client timeout, which bounds the entire exchange, the
+// decorator guards against connections that stall, or trickle data, after the headers arrive.
+func ByTimeoutingResponse(d time.Duration) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil && resp.Body != nil && resp.Body != http.NoBody {
+				resp.Body = &timeoutBody{ReadCloser: resp.Body, timeout: d}
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByWritingResponseToFile returns a RespondDecorator that, after invoking the passed Responder and
+// only if it succeeds, creates (or truncates) the file at path with the passed permissions and
+// streams the remaining response body into it through a buffered writer. Should streaming fail,
+// the decorator removes the partially written file; the returned error then carries both the
+// streaming failure and any failure removing the file.
+func ByWritingResponseToFile(path string, perm os.FileMode) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || resp == nil || resp.Body == nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByWritingResponseToFile", resp.StatusCode, "Failure creating file %s", path)
+			}
+			w := bufio.NewWriter(f)
+			_, err = io.Copy(w, resp.Body)
+			if err == nil {
+				err = w.Flush()
+			}
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				if rerr := os.Remove(path); rerr != nil {
+					err = errors.Join(err, rerr)
+				}
+				return NewErrorWithError(err, "autorest", "ByWritingResponseToFile", resp.StatusCode, "Failure writing response body to %s", path)
+			}
+			return nil
+		})
+	}
+}
+
+// ByHashingResponseBody returns a RespondDecorator that computes, using the named algorithm (one of
+// HashMD5, HashSHA256, or HashCRC64), the digest of the response body as the passed Responder
+// reads it and writes the digest into the byte slice pointed to by dest. Content left unread by the
+// passed Responder is read and hashed once it returns. If the response carries a Content-MD5 (for
+// HashMD5), x-ms-content-sha256 (for HashSHA256), or x-ms-content-crc64 (for HashCRC64, as a
+// little-endian value) header, the decorator returns an error when the digest does not match the
+// base64-encoded value of the header.
+func ByHashingResponseBody(algorithm string, dest *[]byte) RespondDecorator {
+	return byHashingResponseBody(algorithm, dest, func(resp *http.Response) ([]byte, error) {
+		header := contentHashHeader(algorithm)
+		if v := resp.Header.Get(header); header != "" && v != "" {
+			return base64.StdEncoding.DecodeString(v)
+		}
+		return nil, nil
+	})
+}
+
+// ByHashingResponseBodyWithExpected returns a RespondDecorator that behaves like
+// ByHashingResponseBody but returns an error when the digest does not match the supplied expected
+// digest, ignoring any integrity headers.
+func ByHashingResponseBodyWithExpected(algorithm string, expected []byte, dest *[]byte) RespondDecorator {
+	return byHashingResponseBody(algorithm, dest, func(resp *http.Response) ([]byte, error) {
+		return expected, nil
+	})
+}
+
+func byHashingResponseBody(algorithm string, dest *[]byte, expected func(*http.Response) ([]byte, error)) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || resp.Body == nil {
+				return r.Respond(resp)
+			}
+			h := newContentHash(algorithm)
+			if h == nil {
+				return NewErrorWithStatusCode("autorest", "ByHashingResponseBody", resp.StatusCode, "Unsupported hash algorithm %s", algorithm)
+			}
+
+			body := &eofBody{Reader: io.TeeReader(resp.Body, h), Closer: resp.Body}
+			resp.Body = body
+			err := r.Respond(resp)
+			if err != nil {
+				return err
+			}
+			if !body.eof {
+				if _, err = io.Copy(ioutil.Discard, body); err != nil {
+					return NewErrorWithError(err, "autorest", "ByHashingResponseBody", resp.StatusCode, "Failure reading response body")
+				}
+			}
+
+			digest := contentHashSum(h)
+			if dest != nil {
+				*dest = digest
+			}
+			e, err := expected(resp)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByHashingResponseBody", resp.StatusCode, "Failure decoding the expected %s hash", algorithm)
+			}
+			if e != nil && !bytes.Equal(e, digest) {
+				return NewErrorWithStatusCode("autorest", "ByHashingResponseBody", resp.StatusCode, "Response body %s hash %x does not match the expected %x", algorithm, digest, e)
+			}
+			return nil
+		})
+	}
+}
+
+// ByComputingResponseContentHash returns a RespondDecorator that computes, like
+// ByHashingResponseBody, the digest of the response body using the named algorithm (one of HashMD5,
+// HashSHA256, or HashCRC64) and stores it, base64 encoded, in the string pointed to by dest. Like
+// ByHashingResponseBody, it verifies the digest against a Content-MD5, x-ms-content-sha256, or
+// x-ms-content-crc64 header, if present. Paired with WithRequestContentHash, it covers both
+// directions of an exchange.
+func ByComputingResponseContentHash(algorithm string, dest *string) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			var digest []byte
+			err := ByHashingResponseBody(algorithm, &digest)(r).Respond(resp)
+			if digest != nil {
+				*dest = base64.StdEncoding.EncodeToString(digest)
+			}
+			return err
+		})
+	}
+}
+
+// ByVerifyingResponseSignature returns a RespondDecorator that verifies the HMAC signature carried,
+// base64 or hex encoded, by the headerName response header. The HMAC, using the passed algorithm
+// and secret, covers the values of the signedHeaders, if any, each written as "name:value\n" with the
+// name lower-cased and in the order passed, followed by the response body. The body is hashed, by
+// means of a TeeReader, as the passed Responder reads it; any content left unread is then consumed.
+// The decorator returns an Error whose original error is ErrResponseSignatureMismatch if the
+// signature is missing or does not match, and an error if algorithm is unavailable (i.e., its
+// implementation is not linked into the binary).
+func ByVerifyingResponseSignature(secret []byte, headerName string, algorithm crypto.Hash, signedHeaders ...string) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil {
+				return r.Respond(resp)
+			}
+			if !algorithm.Available() {
+				return NewErrorWithStatusCode("autorest", "ByVerifyingResponseSignature", resp.StatusCode, "Unavailable hash algorithm %v", algorithm)
+			}
+			mac := hmac.New(algorithm.New, secret)
+			for _, name := range signedHeaders {
+				fmt.Fprintf(mac, "%s:%s\n", strings.ToLower(name), resp.Header.Get(name))
+			}
+			var body *eofBody
+			if resp.Body != nil {
+				body = &eofBody{Reader: io.TeeReader(resp.Body, mac), Closer: resp.Body}
+				resp.Body = body
+			}
+			err := r.Respond(resp)
+			if err != nil {
+				return err
+			}
+			if body != nil && !body.eof {
+				if _, err = io.Copy(ioutil.Discard, body); err != nil {
+					return NewErrorWithError(err, "autorest", "ByVerifyingResponseSignature", resp.StatusCode, "Failure reading response body")
+				}
+			}
+
+			v := resp.Header.Get(headerName)
+			expected, derr := base64.StdEncoding.DecodeString(v)
+			if derr != nil {
+				expected, derr = hex.DecodeString(v)
+			}
+			if v == "" || derr != nil || !hmac.Equal(expected, mac.Sum(nil)) {
+				return NewErrorWithError(ErrResponseSignatureMismatch, "autorest", "ByVerifyingResponseSignature", resp.StatusCode, "Response signature in %s does not match", headerName)
+			}
+			return nil
+		})
+	}
+}
+
+// ByCheckingContentType returns a RespondDecorator that, before invoking the passed Responder,
+// emits an error unless the media type of the response Content-Type header, ignoring parameters
+// such as charset, matches the passed mediaType (without regard to case). The error includes the
+// received Content-Type and up to the first 512 bytes of the body, which is restored after being
+// read.
+func ByCheckingContentType(mediaType string) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil {
+				ct := resp.Header.Get(headerContentType)
+				mt, _, err := mime.ParseMediaType(ct)
+				if err != nil || !strings.EqualFold(mt, mediaType) {
+					var b []byte
+					if resp.Body != nil {
+						b, _ = peekBody(resp, 512)
+					}
+					return NewErrorWithStatusCode("autorest", "ByCheckingContentType", resp.StatusCode, "Expected Content-Type %s, received %q (\"%s\")",
+						mediaType,
+						ct,
+						b)
+				}
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByUnmarshallingJSON returns a RespondDecorator that decodes a JSON document returned in the
+// response Body into the value pointed to by v. An empty Body (e.g., that of an HTTP 204 No
+// Content response) is not an error and leaves v unmodified. If v is an untyped target (i.e., a
+// *interface{} or *map[string]interface{}), numbers are decoded as json.Number, rather than
+// float64, to preserve the precision of large integers.
+func ByUnmarshallingJSON(v interface{}) RespondDecorator {
+	switch v.(type) {
+	case *interface{}, *map[string]interface{}:
+		return ByUnmarshallingJSONWithOptions(v, (*json.Decoder).UseNumber)
+	}
+	return ByUnmarshallingJSONWithOptions(v)
+}
+
+// ByUnmarshallingJSONAllowingEmpty returns a RespondDecorator that, like ByUnmarshallingJSON,
+// decodes a JSON document returned in the response Body into the value pointed to by v. It first
+// determines, from a Content-Length header of zero or by peeking at the body, whether the body is
+// empty (e.g., for an HTTP 200 OK answering a conditional read) and, if so, skips decoding, leaving v
+// unmodified and returning nil, without reading further.
+func ByUnmarshallingJSONAllowingEmpty(v interface{}) RespondDecorator {
+	decode := ByUnmarshallingJSON(v)(NoopResponder)
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody || resp.Header.Get(headerContentLength) == "0" {
+				return err
+			}
+			b, err := peekBody(resp, 1)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByUnmarshallingJSONAllowingEmpty", resp.StatusCode, "Failure reading response body")
+			}
+			if len(b) == 0 {
+				return nil
+			}
+			return decode.Respond(resp)
+		})
+	}
+}
+
+// ByUnmarshallingJSONSafely returns a RespondDecorator that behaves like ByUnmarshallingJSON but
+// returns an error, rather than decoding, if v is not a non-nil pointer and converts any panic
+// raised while decoding (e.g., by an UnmarshalJSON method of a target constructed by means of
+// reflection) into an error describing the target type.
+func ByUnmarshallingJSONSafely(v interface{}) RespondDecorator {
+	decode := ByUnmarshallingJSON(v)(NoopResponder)
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) (err error) {
+			err = r.Respond(resp)
+			if err != nil {
+				return err
+			}
+			if rv := reflect.ValueOf(v); rv.Kind() != reflect.Ptr || rv.IsNil() {
+				return NewError("autorest", "ByUnmarshallingJSONSafely", "Invoked with %T rather than a non-nil pointer", v)
+			}
+			defer func() {
+				if p := recover(); p != nil {
+					err = NewError("autorest", "ByUnmarshallingJSONSafely", "Decoding JSON into %T panicked: %v", v, p)
+				}
+			}()
+			return decode.Respond(resp)
+		})
+	}
+}
+
+// ByUnmarshallingJSONWithOptions returns a RespondDecorator that, like ByUnmarshallingJSON, decodes
+// a JSON document returned in the response Body into the value pointed to by v. Before decoding,
+// it applies the passed options to the json.Decoder (e.g., to invoke DisallowUnknownFields or
+// UseNumber).
+func ByUnmarshallingJSONWithOptions(v interface{}, opts ...func(*json.Decoder)) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err == nil && resp.Body != nil && resp.Body != http.NoBody {
+				b := bytes.Buffer{}
+				d := json.NewDecoder(io.TeeReader(resp.Body, &b))
+				for _, opt := range opts {
+					opt(d)
+				}
+				err = d.Decode(v)
+				if err == io.EOF {
+					err = nil
+				} else if err != nil {
+					err = fmt.Errorf("Error (%v) occurred decoding JSON (\"%s\")", err, b.String())
+				}
+			}
+			return err
+		})
+	}
+}
+
+// PooledJSONDecoder is a JSON decoder that, unlike json.Decoder, may be reset to read from a new
+// io.Reader and so is suitable for reuse by means of a sync.Pool (see ByUnmarshallingJSONWithPool).
+// A PooledJSONDecoder is not safe for concurrent use.
+type PooledJSONDecoder struct {
+	dec    *json.Decoder
+	src    swappableReader
+	failed bool
+}
+
+// NewPooledJSONDecoder returns a PooledJSONDecoder that must be Reset before use.
+func NewPooledJSONDecoder() *PooledJSONDecoder {
+	return &PooledJSONDecoder{}
+}
+
+// NewJSONDecoderPool returns a sync.Pool of *PooledJSONDecoder for use with
+// ByUnmarshallingJSONWithPool.
+func NewJSONDecoderPool() *sync.Pool {
+	return &sync.Pool{New: func() interface{} { return NewPooledJSONDecoder() }}
+}
+
+// Reset prepares the PooledJSONDecoder to decode from r. Since json.Decoder itself cannot be reset,
+// the underlying json.Decoder is kept only if it remains usable (i.e., its last Decode succeeded and
+// it holds nothing buffered beyond whitespace); otherwise it is replaced by a new one.
+func (pd *PooledJSONDecoder) Reset(r io.Reader) {
+	pd.src.r = r
+	if pd.dec != nil && !pd.failed {
+		if b, _ := ioutil.ReadAll(pd.dec.Buffered()); len(bytes.TrimSpace(b)) == 0 {
+			return
+		}
+	}
+	pd.dec = json.NewDecoder(&pd.src)
+	pd.failed = false
+}
+
+// Decode reads the next JSON-encoded value from the io.Reader passed to Reset and stores it in the
+// value pointed to by v (see json.Decoder.Decode).
+func (pd *PooledJSONDecoder) Decode(v interface{}) error {
+	if pd.dec == nil {
+		pd.Reset(nil)
+	}
+	err := pd.dec.Decode(v)
+	if err != nil {
+		pd.failed = true
+	}
+	return err
+}
+
+// swappableReader is an io.Reader reading from a replaceable io.Reader.
+type swappableReader struct {
+	r io.Reader
+}
+
+func (sr *swappableReader) Read(p []byte) (int, error) {
+	if sr.r == nil {
+		return 0, io.EOF
+	}
+	return sr.r.Read(p)
+}
+
+// ByUnmarshallingJSONWithPool returns a RespondDecorator that decodes a JSON document returned in
+// the response Body into the value returned by v, which is called once per response, using a
+// decoder taken from, and afterwards returned to, pool. Pooled values implementing Reset(io.Reader)
+// and Decode(interface{}) error, such as the *PooledJSONDecoder of NewJSONDecoderPool, are reset to
+// read the body. Since json.Decoder lacks Reset, other values, including *json.Decoder, are
+// discarded and replaced by a *PooledJSONDecoder, which is returned to the pool in their stead, so
+// that, once warmed up, the pool holds only reusable decoders. As with ByUnmarshallingJSON, an empty
+// Body is not an error and the error returned for an invalid Body includes its content.
+func ByUnmarshallingJSONWithPool(pool *sync.Pool, v func() interface{}) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+				return err
+			}
+			d, ok := pool.Get().(interface {
+				Reset(io.Reader)
+				Decode(interface{}) error
+			})
+			if !ok {
+				d = NewPooledJSONDecoder()
+			}
+			target := v()
+			b := bytes.Buffer{}
+			d.Reset(io.TeeReader(resp.Body, &b))
+			err = d.Decode(target)
+			d.Reset(nil)
+			pool.Put(d)
+			if err == io.EOF {
+				err = nil
+			} else if err != nil {
+				err = fmt.Errorf("Error (%v) occurred decoding JSON (\"%s\")", err, b.String())
+			}
+			return err
+		})
+	}
+}
+
+// ByUnmarshallingJSONWithPath returns a RespondDecorator that decodes the node of the JSON document
+// returned in the response Body found at jsonPath into the value pointed to by v. jsonPath is a
+// dot-separated list of object member names (e.g., "properties.output.value"); segments that are
+// non-negative integers also index arrays. An empty jsonPath selects the whole document. It returns
+// an error if the path does not exist. An empty Body is not an error and leaves v unmodified.
+func ByUnmarshallingJSONWithPath(jsonPath string, v interface{}) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+				return err
+			}
+			b, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByUnmarshallingJSONWithPath", resp.StatusCode, "Failure reading response body")
+			}
+			if len(bytes.TrimSpace(b)) == 0 {
+				return nil
+			}
+			node := json.RawMessage(b)
+			if jsonPath != "" {
+				for _, segment := range strings.Split(jsonPath, ".") {
+					if node, err = jsonChild(node, segment); err != nil {
+						return fmt.Errorf("Error (%v) occurred selecting JSON path %s (\"%s\")", err, jsonPath, string(b))
+					}
+				}
+			}
+			if err = json.Unmarshal(node, v); err != nil {
+				return fmt.Errorf("Error (%v) occurred decoding JSON path %s (\"%s\")", err, jsonPath, string(b))
+			}
+			return nil
+		})
+	}
+}
+
+// jsonChild returns the member of the passed JSON object named by segment or, if node is an array
+// and segment a non-negative integer, the element at that index.
+func jsonChild(node json.RawMessage, segment string) (json.RawMessage, error) {
+	if trimmed := bytes.TrimSpace(node); len(trimmed) > 0 && trimmed[0] == '[' {
+		i, err := strconv.Atoi(segment)
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("%q is not an array index", segment)
+		}
+		var elements []json.RawMessage
+		if err := json.Unmarshal(node, &elements); err != nil {
+			return nil, err
+		}
+		if i >= len(elements) {
+			return nil, fmt.Errorf("index %d is out of range", i)
+		}
+		return elements[i], nil
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(node, &members); err != nil {
+		return nil, fmt.Errorf("%q cannot select a member of a non-object", segment)
+	}
+	child, ok := members[segment]
+	if !ok {
+		return nil, fmt.Errorf("member %q does not exist", segment)
+	}
+	return child, nil
+}
+
+// ByUnmarshallingJSONWithContext returns a RespondDecorator that behaves like ByUnmarshallingJSON
+// but aborts decoding once the passed context.Context is done, returning the context error. The
+// body is checked for cancellation before each read and, to unblock a read stalled on a slow
+// connection, closed when the context is done; the decorator returns only once any such close
+// completes.
+func ByUnmarshallingJSONWithContext(ctx context.Context, v interface{}) RespondDecorator {
+	return func(r Responder) Responder {
+		unmarshal := ByUnmarshallingJSON(v)(r)
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil && resp.Body != nil && resp.Body != http.NoBody {
+				body := resp.Body
+				done := make(chan struct{})
+				closed := make(chan struct{})
+				go func() {
+					defer close(closed)
+					select {
+					case <-ctx.Done():
+						body.Close()
+					case <-done:
+					}
+				}()
+				defer func() {
+					close(done)
+					<-closed
+				}()
+				resp.Body = readCloser{Reader: &contextReader{ctx: ctx, r: body}, Closer: body}
+			}
+			err := unmarshal.Respond(resp)
+			if err != nil && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		})
+	}
+}
+
+// ByUnmarshallingJSONWithFallback returns a RespondDecorator that decodes a polymorphic JSON object
+// returned in the response Body. It reads the string value of the named discriminator field, calls
+// the registry function for that value to obtain a pointer to the matching concrete type, decodes
+// the complete object into it, and stores the result in the interface{} pointed to by dest. The
+// registry function keyed by the empty string, if any, serves as the fallback for missing or
+// unregistered discriminator values; without one, such values are an error.
+func ByUnmarshallingJSONWithFallback(discriminator string, registry map[string]func() interface{}, dest *interface{}) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+				return err
+			}
+			b, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByUnmarshallingJSONWithFallback", resp.StatusCode, "Failure reading response body")
+			}
+			fields := map[string]json.RawMessage{}
+			if err = json.Unmarshal(b, &fields); err != nil {
+				return fmt.Errorf("Error (%v) occurred decoding JSON (\"%s\")", err, string(b))
+			}
+			var kind string
+			if raw, ok := fields[discriminator]; ok {
+				if err = json.Unmarshal(raw, &kind); err != nil {
+					return fmt.Errorf("Error (%v) occurred decoding JSON discriminator %s (\"%s\")", err, discriminator, string(raw))
+				}
+			}
+			factory, ok := registry[kind]
+			if !ok {
+				factory, ok = registry[""]
+			}
+			if !ok {
+				return NewErrorWithStatusCode("autorest", "ByUnmarshallingJSONWithFallback", resp.StatusCode, "No type registered for %s %q", discriminator, kind)
+			}
+			v := factory()
+			if err = json.Unmarshal(b, v); err != nil {
+				return fmt.Errorf("Error (%v) occurred decoding JSON (\"%s\")", err, string(b))
+			}
+			*dest = v
+			return nil
+		})
+	}
+}
+
+// ByConditionallyUnmarshallingJSON returns a RespondDecorator that, like ByUnmarshallingJSON,
+// decodes a JSON document returned in the response Body into the value pointed to by v, but only if
+// the response StatusCode is among the set passed. Otherwise, it leaves both v and the body
+// untouched.
+func ByConditionallyUnmarshallingJSON(v interface{}, codes ...int) RespondDecorator {
+	return ByUnmarshallingJSONIf(v, func(resp *http.Response) bool {
+		return ResponseHasStatusCode(resp, codes...)
+	})
+}
+
+// ByUnmarshallingJSONIf returns a RespondDecorator that, like ByUnmarshallingJSON, decodes a JSON
+// document returned in the response Body into the value pointed to by v, but only if the passed
+// predicate returns true for the http.Response. Otherwise, it leaves both v and the body untouched.
+func ByUnmarshallingJSONIf(v interface{}, predicate func(*http.Response) bool) RespondDecorator {
+	return func(r Responder) Responder {
+		unmarshal := ByUnmarshallingJSON(v)(r)
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil && predicate(resp) {
+				return unmarshal.Respond(resp)
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByUnmarshallingJSONOrError returns a RespondDecorator that decodes a JSON document returned in the
+// response Body into the value pointed to by either success or errValue. If the response
+// StatusCode is among the passed errorCodes, it decodes the body into errValue and returns a
+// JSONBodyError carrying errValue; otherwise, it decodes the body into success.
+func ByUnmarshallingJSONOrError(success interface{}, errValue interface{}, errorCodes ...int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || !ResponseHasStatusCode(resp, errorCodes...) {
+				return ByUnmarshallingJSON(success)(r).Respond(resp)
+			}
+			err := ByUnmarshallingJSON(errValue)(r).Respond(resp)
+			if err == nil {
+				err = JSONBodyError{StatusCode: resp.StatusCode, Value: errValue}
+			}
+			return err
+		})
+	}
+}
+
+// ByUnmarshallingJSONInto returns a RespondDecorator that decodes the value of the named top-level
+// field of the JSON object returned in the response Body into the value pointed to by v. The field
+// name is matched exactly, if possible, and otherwise without regard to case. If the object lacks
+// the field, v is left unmodified.
+func ByUnmarshallingJSONInto(field string, v interface{}) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err == nil {
+				b := bytes.Buffer{}
+				fields := map[string]json.RawMessage{}
+				d := json.NewDecoder(io.TeeReader(resp.Body, &b))
+				err = d.Decode(&fields)
+				if err == nil {
+					raw, ok := fields[field]
+					if !ok {
+						for k, f := range fields {
+							if strings.EqualFold(k, field) {
+								raw, ok = f, true
+								break
+							}
+						}
+					}
+					if ok {
+						err = json.Unmarshal(raw, v)
+					}
+				}
+				if err != nil {
+					err = fmt.Errorf("Error (%v) occurred decoding JSON field %s (\"%s\")", err, field, b.String())
+				}
+			}
+			return err
+		})
+	}
+}
+
+// ByValidatingResponseSchema returns a RespondDecorator that validates the JSON document returned in
+// the response Body against the passed JSON Schema, using the DefaultSchemaValidator, before
+// invoking the passed Responder. The body is restored after being read so that subsequent
+// decorators see it unmodified. The returned error lists all violated constraints.
+func ByValidatingResponseSchema(schema []byte) RespondDecorator {
+	return ByValidatingResponseSchemaWithValidator(schema, DefaultSchemaValidator)
+}
+
+// ByValidatingResponseSchemaWithValidator returns a RespondDecorator that behaves like
+// ByValidatingResponseSchema but uses the supplied SchemaValidator.
+func ByValidatingResponseSchemaWithValidator(schema []byte, validator SchemaValidator) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp != nil && resp.Body != nil {
+				var v interface{}
+				b := bytes.Buffer{}
+				d := json.NewDecoder(io.TeeReader(resp.Body, &b))
+				err := d.Decode(&v)
+				resp.Body = readCloser{Reader: io.MultiReader(&b, resp.Body), Closer: resp.Body}
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByValidatingResponseSchema", resp.StatusCode, "Failure decoding JSON (\"%s\")", b.String())
+				}
+				violations, err := validator.Validate(schema, v)
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByValidatingResponseSchema", resp.StatusCode, "Failure validating JSON")
+				}
+				if len(violations) > 0 {
+					return NewErrorWithStatusCode("autorest", "ByValidatingResponseSchema", resp.StatusCode, "JSON failed schema validation: %s",
+						strings.Join(violations, "; "))
+				}
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByUnmarshallingJSONList returns a RespondDecorator that decodes a page of a list returned in the
+// response Body in the form common to Azure list APIs (i.e., {"value": [...], "nextLink": "..."}).
+// It decodes the value array into the slice pointed to by slice and, if nextLink is not nil, sets
+// the string it points to from the nextLink field (or to the empty string on the last page).
+func ByUnmarshallingJSONList(slice interface{}, nextLink *string) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err == nil {
+				b := bytes.Buffer{}
+				page := struct {
+					Value    json.RawMessage `json:"value"`
+					NextLink string          `json:"nextLink"`
+				}{}
+				d := json.NewDecoder(io.TeeReader(resp.Body, &b))
+				err = d.Decode(&page)
+				if err == nil && len(page.Value) > 0 {
+					err = json.Unmarshal(page.Value, slice)
+				}
+				if err != nil {
+					err = fmt.Errorf("Error (%v) occurred decoding JSON list (\"%s\")", err, b.String())
+				} else if nextLink != nil {
+					*nextLink = page.NextLink
+				}
+			}
+			return err
+		})
+	}
+}
+
+// ByFollowingNextLink returns a RespondDecorator that, after invoking the passed Responder, follows
+// the nextLink field of the JSON list page returned in the response Body (see
+// ByUnmarshallingJSONList). It issues a GET for each subsequent page through the supplied Sender,
+// copying the headers of the originating request, and passes each page's response to accumulate
+// before closing its body. accumulate thus sees the second and every later page, but not the first:
+// the first page is left to the passed Responder, for which the body is read ahead and restored. It
+// stops once a page lacks a nextLink (or it is empty), returns an error if a nextLink repeats the URL
+// of a page already retrieved (which would otherwise loop forever), and returns the context error
+// should the context of the originating http.Request be done between pages.
+func ByFollowingNextLink(sender Sender, accumulate func(resp *http.Response) error) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || resp.Body == nil {
+				return r.Respond(resp)
+			}
+			b, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByFollowingNextLink", resp.StatusCode, "Failure reading response body")
+			}
+			resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+			if err = r.Respond(resp); err != nil {
+				return err
+			}
+
+			ctx := context.Background()
+			var header http.Header
+			seen := map[string]bool{}
+			base := resp.Request
+			if base != nil {
+				ctx = base.Context()
+				header = base.Header
+				if base.URL != nil {
+					seen[base.URL.String()] = true
+				}
+			}
+			for link := nextLinkOf(b); link != ""; link = nextLinkOf(b) {
+				if err = ctx.Err(); err != nil {
+					return err
+				}
+				u, err := url.Parse(link)
+				if err == nil && base != nil {
+					u = base.URL.ResolveReference(u)
+				}
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByFollowingNextLink", resp.StatusCode, "Failure parsing nextLink %s", link)
+				}
+				if seen[u.String()] {
+					return NewError("autorest", "ByFollowingNextLink", "nextLink %s repeats a page already retrieved", u)
+				}
+				seen[u.String()] = true
+				req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByFollowingNextLink", resp.StatusCode, "Failure creating request for %s", u)
+				}
+				if header != nil {
+					req.Header = header.Clone()
+				}
+				page, err := sender.Do(req)
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByFollowingNextLink", resp.StatusCode, "Failure sending request to %s", u)
+				}
+				if page.Request == nil {
+					page.Request = req
+				}
+				base = page.Request
+				b = nil
+				if page.Body != nil {
+					b, err = ioutil.ReadAll(page.Body)
+					page.Body.Close()
+					if err != nil {
+						return NewErrorWithError(err, "autorest", "ByFollowingNextLink", page.StatusCode, "Failure reading response body")
+					}
+					page.Body = ioutil.NopCloser(bytes.NewReader(b))
+				}
+				err = accumulate(page)
+				if page.Body != nil {
+					page.Body.Close()
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// nextLinkOf returns the nextLink field of the passed JSON list page, or the empty string if it is
+// absent or the page cannot be decoded.
+func nextLinkOf(b []byte) string {
+	page := struct {
+		NextLink string `json:"nextLink"`
+	}{}
+	json.Unmarshal(b, &page)
+	return page.NextLink
+}
+
+// ByUnmarshallingCSV returns a RespondDecorator that decodes all records of a CSV document
+// returned in the response Body into the slice pointed to by records. The passed options, if any,
+// configure the csv.Reader before decoding (e.g., to set Comma or LazyQuotes). Errors identify the
+// line on which parsing failed.
+func ByUnmarshallingCSV(records *[][]string, opts ...func(*csv.Reader)) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err == nil && resp.Body != nil {
+				cr := csv.NewReader(resp.Body)
+				for _, opt := range opts {
+					opt(cr)
+				}
+				var rs [][]string
+				rs, err = cr.ReadAll()
+				var pe *csv.ParseError
+				if errors.As(err, &pe) {
+					err = fmt.Errorf("Error (%v) occurred decoding CSV on line %d", pe.Err, pe.Line)
+				} else if err != nil {
+					err = fmt.Errorf("Error (%v) occurred decoding CSV", err)
+				} else {
+					*records = rs
+				}
+			}
+			return err
+		})
+	}
+}
+
+// ByUnmarshallingJSONStream returns a RespondDecorator that reads a newline-delimited JSON (i.e.,
+// NDJSON or JSON Lines) document returned in the response Body one line at a time and passes each
+// line, as a json.RawMessage, to each. Blank lines are skipped. Processing stops at the first line
+// that is not valid JSON, returning an error identifying the line, or at the first error returned
+// by each, which is returned unchanged.
+func ByUnmarshallingJSONStream(each func(json.RawMessage) error) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+				return err
+			}
+			br := bufio.NewReader(resp.Body)
+			for line := 1; ; line++ {
+				b, err := br.ReadBytes('\n')
+				if err != nil && err != io.EOF {
+					return NewErrorWithError(err, "autorest", "ByUnmarshallingJSONStream", resp.StatusCode, "Failure reading line %d", line)
+				}
+				if v := bytes.TrimSpace(b); len(v) > 0 {
+					if !json.Valid(v) {
+						return fmt.Errorf("Error occurred decoding JSON on line %d (\"%s\")", line, v)
+					}
+					if e := each(json.RawMessage(v)); e != nil {
+						return e
+					}
+				}
+				if err == io.EOF {
+					return nil
+				}
+			}
+		})
+	}
+}
+
+// ByAppendingToSlice returns a RespondDecorator that decodes a JSON array returned in the response
+// Body and appends its elements to the slice pointed to by slicePtr, which may be of any element
+// type supported by json.Unmarshal. It returns an error if slicePtr is not a non-nil pointer to a
+// slice. Successive responses (e.g., pages of a list) thus accumulate into the same slice.
+func ByAppendingToSlice(slicePtr interface{}) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil {
+				return err
+			}
+			v := reflect.ValueOf(slicePtr)
+			if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+				return NewError("autorest", "ByAppendingToSlice", "Invoked with %T rather than a pointer to a slice", slicePtr)
+			}
+			if resp.Body == nil || resp.Body == http.NoBody {
+				return nil
+			}
+			b := bytes.Buffer{}
+			page := reflect.New(v.Elem().Type())
+			err = json.NewDecoder(io.TeeReader(resp.Body, &b)).Decode(page.Interface())
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("Error (%v) occurred decoding JSON (\"%s\")", err, b.String())
+			}
+			v.Elem().Set(reflect.AppendSlice(v.Elem(), page.Elem()))
+			return nil
+		})
+	}
+}
+
+// ByParallelUnmarshalling returns a RespondDecorator that reads the JSON object returned in the
+// response Body once and then, concurrently, decodes the value of each of its top-level members
+// named in targets into the value to which the name maps (which must be a pointer, as for
+// json.Unmarshal). Members absent from the response leave their targets unmodified. Failures for
+// individual members are joined, ordered by their messages, into a single error (see errors.Join).
+func ByParallelUnmarshalling(targets map[string]interface{}) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+				return err
+			}
+			b, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByParallelUnmarshalling", resp.StatusCode, "Failure reading response body")
+			}
+			if len(bytes.TrimSpace(b)) == 0 {
+				return nil
+			}
+			var members map[string]json.RawMessage
+			if err := json.Unmarshal(b, &members); err != nil {
+				return fmt.Errorf("Error (%v) occurred decoding JSON (\"%s\")", err, string(b))
+			}
+
+			var wg sync.WaitGroup
+			var mu sync.Mutex
+			var errs []error
+			for name, v := range targets {
+				raw, ok := members[name]
+				if !ok {
+					continue
+				}
+				wg.Add(1)
+				go func(name string, raw json.RawMessage, v interface{}) {
+					defer wg.Done()
+					if err := json.Unmarshal(raw, v); err != nil {
+						mu.Lock()
+						errs = append(errs, fmt.Errorf("Error (%v) occurred decoding JSON member %s", err, name))
+						mu.Unlock()
+					}
+				}(name, raw, v)
+			}
+			wg.Wait()
+			sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+			return errors.Join(errs...)
+		})
+	}
+}
+
+// ByTranscodingBody returns a RespondDecorator that, before invoking the passed Responder, converts
+// the response body from the from format to the to format and updates the Content-Type header to
+// match. Only conversion from "xml" to "json" is supported; it follows the Badgerfish convention
+// (element names become object keys, attributes become members prefixed with "@", and text becomes
+// the "$" member) so that, for example, ByUnmarshallingJSON can decode legacy XML responses.
+func ByTranscodingBody(from, to string) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if !strings.EqualFold(from, "xml") || !strings.EqualFold(to, "json") {
+				return NewError("autorest", "ByTranscodingBody", "Unsupported transcoding from %s to %s", from, to)
+			}
+			if resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+				return r.Respond(resp)
+			}
+			b := bytes.Buffer{}
+			v, err := xmlToBadgerfish(io.TeeReader(resp.Body, &b))
+			if err != nil {
+				return fmt.Errorf("Error (%v) occurred transcoding XML (\"%s\")", err, b.String())
+			}
+			j, err := json.Marshal(v)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByTranscodingBody", resp.StatusCode, "Failure encoding JSON")
+			}
+			resp.Body = readCloser{Reader: bytes.NewReader(j), Closer: resp.Body}
+			resp.ContentLength = int64(len(j))
+			if resp.Header == nil {
+				resp.Header = http.Header{}
+			}
+			resp.Header.Set(headerContentType, mimeTypeJSON)
+			resp.Header.Del(headerContentLength)
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByUnmarshallingXML returns a RespondDecorator that decodes an XML document returned in the
+// response Body into the value pointed to by v. As with ByUnmarshallingJSON, an empty Body, as
+// determined from a Content-Length header of zero or by peeking at the body, is not an error and
+// leaves v unmodified.
+func ByUnmarshallingXML(v interface{}) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody || resp.Header.Get(headerContentLength) == "0" {
+				return err
+			}
+			p, err := peekBody(resp, 1)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByUnmarshallingXML", resp.StatusCode, "Failure reading response body")
+			}
+			if len(p) == 0 {
+				return nil
+			}
+			b := bytes.Buffer{}
+			d := xml.NewDecoder(io.TeeReader(resp.Body, &b))
+			if err = d.Decode(v); err != nil {
+				err = fmt.Errorf("Error (%v) occurred decoding XML (\"%s\")", err, b.String())
+			}
+			return err
+		})
+	}
+}
+
+// ByLoggingResponse returns a RespondDecorator that, after invoking the passed Responder, logs the
+// response status code, headers, and elapsed time to the supplied Logger. The elapsed time is
+// measured from the start time carried by the context of the response's http.Request (see
+// WithStartTime, which SendWithSender applies), that is, since the request was sent, if any, and
+// otherwise from when the decorator is invoked.
+func ByLoggingResponse(logger Logger) RespondDecorator {
+	return ByLoggingResponseWithBody(logger, 0)
+}
+
+// BySelectiveDebugging returns a RespondDecorator that, before invoking the passed Responder, logs
+// the method and URL of the request along with the status, headers, and up to DefaultDebugBodyBytes
+// of the body of any response whose StatusCode is among triggerCodes (e.g., 429, 500, or 503). The
+// logged bytes are read ahead and then restored so subsequent decorators still see the complete
+// body. It does nothing for other responses, so it may remain in production pipelines.
+func BySelectiveDebugging(logger Logger, triggerCodes ...int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || !ResponseHasStatusCode(resp, triggerCodes...) {
+				return r.Respond(resp)
+			}
+			var b []byte
+			if resp.Body != nil {
+				b, _ = peekBody(resp, DefaultDebugBodyBytes)
+			}
+			keyvals := []interface{}{}
+			if resp.Request != nil {
+				keyvals = append(keyvals, "method", resp.Request.Method)
+				if resp.Request.URL != nil {
+					keyvals = append(keyvals, "url", resp.Request.URL.String())
+				}
+			}
+			logger.Log(append(keyvals,
+				"status", resp.StatusCode,
+				"headers", resp.Header,
+				"body", string(b))...)
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByLoggingResponseWithBody returns a RespondDecorator that behaves like ByLoggingResponse but also
+// logs up to maxBodyBytes of the response body. The logged bytes are read ahead of the passed
+// Responder and then restored so subsequent decorators still see the complete body.
+func ByLoggingResponseWithBody(logger Logger, maxBodyBytes int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			start := DefaultClock.Now()
+			if resp != nil && resp.Request != nil {
+				if t := StartTimeFromContext(resp.Request.Context()); !t.IsZero() {
+					start = t
+				}
+			}
+			var b []byte
+			if maxBodyBytes > 0 && resp != nil && resp.Body != nil {
+				b, _ = peekBody(resp, int64(maxBodyBytes))
+			}
+			err := r.Respond(resp)
+			if resp != nil {
+				keyvals := []interface{}{
+					"status", resp.StatusCode,
+					"headers", resp.Header,
+					"elapsed", DefaultClock.Now().Sub(start),
+				}
+				if maxBodyBytes > 0 {
+					keyvals = append(keyvals, "body", string(b))
+				}
+				logger.Log(keyvals...)
+			}
+			return err
+		})
+	}
+}
+
+// ByRetryingOnStatusCode returns a RespondDecorator that, while the response StatusCode is among
+// the set passed, re-sends the originating request through the supplied Sender up to maxRetries
+// times, backing off exponentially from DefaultRetryBackoff, up to MaxRetryBackoff, between
+// attempts. Backing off stops, returning the context error, if the context of the response's
+// http.Request is done. Responses without an http.Request are passed along as is. The final response
+// replaces the passed http.Response before invoking the passed Responder; prior response bodies are
+// drained and closed before re-sending. Request bodies are restored by means of http.Request.GetBody, so requests with
+// a body lacking GetBody cannot be re-sent.
+func ByRetryingOnStatusCode(sender Sender, maxRetries int, codes ...int) RespondDecorator {
+	return ByRetryingOnStatusCodeWithBackoff(sender, maxRetries, DefaultRetryBackoff, codes...)
+}
+
+// ByRetryingOnStatusCodeWithBackoff returns a RespondDecorator that behaves like
+// ByRetryingOnStatusCode but backs off exponentially from the supplied backoff time.Duration
+// (which may be zero).
+func ByRetryingOnStatusCodeWithBackoff(sender Sender, maxRetries int, backoff time.Duration, codes ...int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			for attempt := 0; attempt < maxRetries && resp != nil && resp.Request != nil && ResponseHasStatusCode(resp, codes...); attempt++ {
+				if err := sleepWithContext(resp.Request.Context(), retryBackoff(backoff, attempt)); err != nil {
+					return err
+				}
+				if err := resendRequest(sender, resp, "ByRetryingOnStatusCode"); err != nil {
+					return err
+				}
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// retryBackoff returns the delay before the retry following the passed number of prior attempts,
+// doubling backoff for each attempt without exceeding MaxRetryBackoff, unless backoff itself does.
+func retryBackoff(backoff time.Duration, attempt int) time.Duration {
+	d := backoff
+	for i := 0; i < attempt && d > 0 && d < MaxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > MaxRetryBackoff && backoff < MaxRetryBackoff {
+		d = MaxRetryBackoff
+	}
+	return d
+}
+
+// ByWaitingForEventualConsistency returns a RespondDecorator that, while retryPredicate reports
+// true for the response (e.g., an HTTP 404 Not Found for a resource just created), waits and then
+// re-sends the originating request through the supplied Sender. Waits back off exponentially from
+// DefaultRetryBackoff, up to MaxRetryBackoff, and, in total, do not exceed maxWait; once maxWait is
+// exhausted, the last response is passed along as is. Waiting stops, returning the context error,
+// if the context of the response's http.Request is done. The final response replaces the passed
+// http.Response before invoking the passed Responder; prior response bodies are drained and closed
+// before re-sending. Request bodies are restored by means of http.Request.GetBody, so requests with
+// a body lacking GetBody cannot be re-sent.
+func ByWaitingForEventualConsistency(sender Sender, maxWait time.Duration, retryPredicate func(*http.Response) bool) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			deadline := DefaultClock.Now().Add(maxWait)
+			for attempt := 0; resp != nil && resp.Request != nil && retryPredicate(resp); attempt++ {
+				remaining := deadline.Sub(DefaultClock.Now())
+				if remaining <= 0 {
+					break
+				}
+				d := retryBackoff(DefaultRetryBackoff, attempt)
+				if d > remaining {
+					d = remaining
+				}
+				if err := sleepWithContext(resp.Request.Context(), d); err != nil {
+					return err
+				}
+				if err := resendRequest(sender, resp, "ByWaitingForEventualConsistency"); err != nil {
+					return err
+				}
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByCircuitBreaker returns a RespondDecorator implementing a circuit breaker shared by every
+// Responder it decorates. A response fails if the passed Responder returns an error or its status
+// code indicates a transient failure (i.e., 408, 429, 500, 502, 503, or 504). While the circuit is
+// closed, responses pass through; after failureThreshold consecutive failures, the circuit opens.
+// While open, the decorator closes the response body and returns an Error, whose original error is
+// ErrCircuitOpen, without invoking the passed Responder. Once openDuration elapses, the circuit is
+// half-open and admits a single response at a time: any failure re-opens the circuit, while
+// successThreshold consecutive successes close it. A panic in the passed Responder counts as a
+// failure. Thresholds less than one are treated as one. The decorator is safe for concurrent use.
+func ByCircuitBreaker(failureThreshold, successThreshold int, openDuration time.Duration) RespondDecorator {
+	cb := &circuitBreaker{
+		failureThreshold: failureThreshold,
+		successThreshold: successThreshold,
+		openDuration:     openDuration,
+	}
+	if cb.failureThreshold < 1 {
+		cb.failureThreshold = 1
+	}
+	if cb.successThreshold < 1 {
+		cb.successThreshold = 1
+	}
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			probe, until, ok := cb.allow()
+			if !ok {
+				statusCode := UndefinedStatusCode
+				if resp != nil {
+					statusCode = resp.StatusCode
+					if resp.Body != nil {
+						resp.Body.Close()
+					}
+				}
+				return NewErrorWithError(ErrCircuitOpen, "autorest", "ByCircuitBreaker", statusCode, "Circuit open until %v", until)
+			}
+			failed := true
+			defer func() { cb.record(probe, failed) }()
+			err := r.Respond(resp)
+			failed = err != nil || (resp != nil && containsInt(retriableStatusCodes, resp.StatusCode))
+			return err
+		})
+	}
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker holds the state shared by the Responders decorated by ByCircuitBreaker.
+type circuitBreaker struct {
+	failureThreshold int
+	successThreshold int
+	openDuration     time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	count    int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow reports whether a response may pass through the circuit, whether it does so as the probe
+// of a half-open circuit, and, if it may not, when the circuit will next admit one. An open circuit
+// whose openDuration has elapsed turns half-open.
+func (cb *circuitBreaker) allow() (bool, time.Time, bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	until := cb.openedAt.Add(cb.openDuration)
+	if cb.state == circuitOpen {
+		if DefaultClock.Now().Before(until) {
+			return false, until, false
+		}
+		cb.state = circuitHalfOpen
+		cb.count = 0
+	}
+	if cb.state == circuitHalfOpen {
+		if cb.probing {
+			return false, until, false
+		}
+		cb.probing = true
+		return true, time.Time{}, true
+	}
+	return false, time.Time{}, true
+}
+
+// record updates the circuit with the outcome of a response admitted by allow. Outcomes of responses
+// admitted while the circuit was in another state are ignored.
+func (cb *circuitBreaker) record(probe, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch {
+	case !probe && cb.state == circuitClosed:
+		if !failed {
+			cb.count = 0
+		} else if cb.count++; cb.count >= cb.failureThreshold {
+			cb.trip()
+		}
+	case probe && cb.state == circuitHalfOpen:
+		cb.probing = false
+		if failed {
+			cb.trip()
+		} else if cb.count++; cb.count >= cb.successThreshold {
+			cb.state = circuitClosed
+			cb.count = 0
+		}
+	}
+}
+
+// trip opens the circuit; the caller must hold cb.mu.
+func (cb *circuitBreaker) trip() {
+	cb.state = circuitOpen
+	cb.count = 0
+	cb.openedAt = DefaultClock.Now()
+}
+
+// ByRetryAfterRespecting returns a RespondDecorator that, while the response is an HTTP 429 Too
+// Many Requests or 503 Service Unavailable carrying a Retry-After header, waits the indicated
+// duration (see GetRetryAfter) and then re-sends the originating request through the supplied
+// Sender, up to maxRetries times. Waiting stops, returning the context error, if the context of the
+// response's http.Request is done. Responses without an http.Request are passed along as is. The
+// final response replaces the passed http.Response before invoking the passed Responder; prior
+// response bodies are drained and closed before re-sending. Request bodies are restored by means of
+// http.Request.GetBody, so requests with a body lacking GetBody cannot be re-sent.
+func ByRetryAfterRespecting(sender Sender, maxRetries int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			for attempt := 0; attempt < maxRetries && resp != nil && resp.Request != nil && ResponseHasStatusCode(resp, http.StatusTooManyRequests, http.StatusServiceUnavailable); attempt++ {
+				d, ok := GetRetryAfter(resp)
+				if !ok {
+					break
+				}
+				if err := sleepWithContext(resp.Request.Context(), d); err != nil {
+					return err
+				}
+				if err := resendRequest(sender, resp, "ByRetryAfterRespecting"); err != nil {
+					return err
+				}
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// resendRequest re-sends the request of the passed http.Response through the passed Sender,
+// restoring the request body by means of http.Request.GetBody, and replaces the passed
+// http.Response with the result. The body of the passed http.Response is drained and closed before
+// the request is re-sent, so that its connection is released. Each attempt is counted for an
+// enclosing ByRecordingRetryCount, if any. It returns an error if the passed http.Response lacks a
+// request, the request has a body but lacks GetBody, or the Sender returns neither a response nor
+// an error.
+func resendRequest(sender Sender, resp *http.Response, method string) error {
+	req := resp.Request
+	if req == nil {
+		return NewErrorWithStatusCode("autorest", method, resp.StatusCode, "Response lacks the http.Request to re-send")
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return NewErrorWithError(err, "autorest", method, resp.StatusCode, "Failure restoring request body")
+		}
+		req.Body = body
+	} else if req.Body != nil && req.Body != http.NoBody {
+		return NewErrorWithStatusCode("autorest", method, resp.StatusCode, "Unable to restore the request body to re-send to %s", req.URL)
+	}
+	if resp.Body != nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if resent, ok := req.Context().Value(contextResendCountKey).(*int32); ok {
+		atomic.AddInt32(resent, 1)
+	}
+	next, err := sender.Do(req)
+	if err != nil {
+		return NewErrorWithError(err, "autorest", method, resp.StatusCode, "Failure re-sending request to %s", req.URL)
+	}
+	if next == nil {
+		return NewErrorWithStatusCode("autorest", method, resp.StatusCode, "Sender returned no response re-sending request to %s", req.URL)
+	}
+	if next.Request == nil {
+		next.Request = req
+	}
+	*resp = *next
+	return nil
+}
+
+// ByFollowingAuthChallenge returns a RespondDecorator that, if the response is an HTTP 401
+// Unauthorized carrying a Bearer challenge in its WWW-Authenticate header (e.g., Bearer
+// realm="https://example.azurecr.io/oauth2/token",service="example.azurecr.io",scope="repository:a:pull"),
+// obtains a token for the challenge's realm, service, and scope from tokenProvider and re-sends the
+// originating request, bearing the token in its Authorization header, once through the supplied
+// Sender. The new response replaces the passed http.Response before invoking the passed Responder;
+// the prior response body is drained and closed before re-sending. Request bodies are restored by
+// means of http.Request.GetBody, so requests with a body lacking GetBody cannot be re-sent. Other
+// responses pass through unmodified.
+func ByFollowingAuthChallenge(sender Sender, tokenProvider func(realm, service, scope string) (string, error)) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || resp.Request == nil || resp.StatusCode != http.StatusUnauthorized {
+				return r.Respond(resp)
+			}
+			params, ok := parseBearerChallenge(resp.Header.Values(headerWWWAuthenticate))
+			if !ok {
+				return r.Respond(resp)
+			}
+			token, err := tokenProvider(params["realm"], params["service"], params["scope"])
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByFollowingAuthChallenge", resp.StatusCode, "Failure obtaining token for realm %s", params["realm"])
+			}
+			req := resp.Request.Clone(resp.Request.Context())
+			req.Header.Set(headerAuthorization, "Bearer "+token)
+			resp.Request = req
+			if err := resendRequest(sender, resp, "ByFollowingAuthChallenge"); err != nil {
+				return err
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// parseBearerChallenge returns the parameters, keyed by their lower-cased names, of the first
+// Bearer challenge among the passed WWW-Authenticate header values and whether one was found.
+func parseBearerChallenge(values []string) (map[string]string, bool) {
+	for _, v := range values {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(v), " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			continue
+		}
+		params := map[string]string{}
+		for _, param := range splitLinkHeader(rest, ',') {
+			name, value, found := strings.Cut(param, "=")
+			if !found {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if uq, err := strconv.Unquote(value); err == nil {
+				value = uq
+			}
+			params[strings.ToLower(strings.TrimSpace(name))] = value
+		}
+		return params, true
+	}
+	return nil, false
+}
+
+// ByRedirectFollowing returns a RespondDecorator that, while the response is an HTTP 307 Temporary
+// Redirect or 308 Permanent Redirect, re-sends the originating request, including its body, through
+// the supplied Sender to the URL given by the Location header. It follows at most maxRedirects
+// redirects. The final response replaces the passed http.Response before invoking the passed
+// Responder; prior response bodies are closed. Request bodies are restored by means of
+// http.Request.GetBody, so requests with a body lacking GetBody cannot be redirected. Redirect
+// responses lacking the originating http.Request (and its URL) cannot be followed and return an
+// error.
+func ByRedirectFollowing(sender Sender, maxRedirects int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			for redirect := 0; redirect < maxRedirects && resp != nil && ResponseHasStatusCode(resp, http.StatusTemporaryRedirect, http.StatusPermanentRedirect); redirect++ {
+				location := GetPollingLocation(resp)
+				if location == "" {
+					return NewErrorWithStatusCode("autorest", "ByRedirectFollowing", resp.StatusCode, "Location header missing from redirect response")
+				}
+				if resp.Request == nil || resp.Request.URL == nil {
+					return NewErrorWithStatusCode("autorest", "ByRedirectFollowing", resp.StatusCode, "Redirect response lacks the originating http.Request")
+				}
+				u, err := resp.Request.URL.Parse(location)
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByRedirectFollowing", resp.StatusCode, "Failure parsing redirect location %s", location)
+				}
+
+				req := resp.Request.Clone(resp.Request.Context())
+				req.URL = u
+				req.Host = ""
+				if req.GetBody != nil {
+					req.Body, err = req.GetBody()
+					if err != nil {
+						return NewErrorWithError(err, "autorest", "ByRedirectFollowing", resp.StatusCode, "Failure restoring request body")
+					}
+				} else if req.Body != nil && req.Body != http.NoBody {
+					return NewErrorWithStatusCode("autorest", "ByRedirectFollowing", resp.StatusCode, "Unable to restore the request body for redirect to %s", u)
+				}
+
+				next, err := sender.Do(req)
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByRedirectFollowing", resp.StatusCode, "Failure sending redirected request to %s", u)
+				}
+				if next == nil {
+					return NewErrorWithStatusCode("autorest", "ByRedirectFollowing", resp.StatusCode, "Sender returned no response for redirected request to %s", u)
+				}
+				if resp.Body != nil {
+					resp.Body.Close()
+				}
+				*resp = *next
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ByRecordingFinalURL returns a RespondDecorator that, after invoking the passed Responder, stores
+// a copy of the URL of the response's http.Request in *dest. Since the http.Client follows redirects,
+// this is the URL that produced the response, which may differ from the one originally prepared.
+// *dest is set to nil if the response lacks a request or its URL.
+func ByRecordingFinalURL(dest **url.URL) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			*dest = nil
+			if resp != nil && resp.Request != nil && resp.Request.URL != nil {
+				u := *resp.Request.URL
+				*dest = &u
+			}
+			return err
+		})
+	}
+}
+
+// ByCapturingRedirectURL returns a RespondDecorator that, for responses whose StatusCode is among
+// the passed codes (defaulting to HTTP 301, 302, 307, and 308), parses the Location header, resolved
+// against the request URL, into the url.URL pointed to by dest (e.g., to capture a pre-signed SAS
+// URL rather than follow it). For such responses, it also suppresses the error a status code check
+// (e.g., WithErrorUnlessStatusCode or WithErrorUnlessStatusCodeRange) emits for the response, so it
+// should follow such checks in the decorator list; all other errors returned by the passed Responder
+// are returned as is, without capturing the URL. It returns an error if the Location header is
+// missing or cannot be parsed.
+func ByCapturingRedirectURL(dest *url.URL, codes ...int) RespondDecorator {
+	if len(codes) == 0 {
+		codes = []int{http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect}
+	}
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if resp == nil || !ResponseHasStatusCode(resp, codes...) || (err != nil && !isStatusCodeError(err, resp)) {
+				return err
+			}
+			location := resp.Header.Get(headerLocation)
+			if location == "" {
+				return NewErrorWithStatusCode("autorest", "ByCapturingRedirectURL", resp.StatusCode, "Location header missing from redirect response")
+			}
+			u, perr := url.Parse(location)
+			if perr != nil {
+				return NewErrorWithError(perr, "autorest", "ByCapturingRedirectURL", resp.StatusCode, "Failure parsing redirect location %s", location)
+			}
+			if resp.Request != nil && resp.Request.URL != nil {
+				u = resp.Request.URL.ResolveReference(u)
+			}
+			*dest = *u
+			return nil
+		})
+	}
+}
+
+// isStatusCodeError reports whether the passed error is the *DetailedError a status code check
+// (e.g., WithErrorUnlessStatusCode) emits for resp solely because of its StatusCode.
+func isStatusCodeError(err error, resp *http.Response) bool {
+	de, ok := err.(*DetailedError)
+	return ok && de.Response == resp && de.Original() == nil && de.StatusCode() == resp.StatusCode
+}
+
+// ByMeasuringLatency returns a RespondDecorator that records, into the time.Duration pointed to by
+// dest, the time elapsed until the passed Responder returns. The time is measured from the start
+// time carried by the context of the response's http.Request (see WithStartTime, which
+// SendWithSender applies), if any, and otherwise from when the decorator is invoked.
+func ByMeasuringLatency(dest *time.Duration) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			start := DefaultClock.Now()
+			if resp != nil && resp.Request != nil {
+				if t := StartTimeFromContext(resp.Request.Context()); !t.IsZero() {
+					start = t
+				}
+			}
+			err := r.Respond(resp)
+			*dest = DefaultClock.Now().Sub(start)
+			return err
+		})
+	}
+}
+
+// ByObservingStatusCode returns a RespondDecorator that, after invoking the passed Responder and
+// whether or not it fails, passes the response StatusCode to observer (e.g., to increment a metrics
+// counter).
+func ByObservingStatusCode(observer func(statusCode int)) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if resp != nil {
+				observer(resp.StatusCode)
+			}
+			return err
+		})
+	}
+}
+
+// ByAuditingResponse returns a RespondDecorator that, after invoking the passed Responder, writes a
+// single-line audit entry describing the response to w. The entry includes the time, the request
+// method and URL, the response status code, headers, and latency (measured as by
+// ByMeasuringLatency), and up to DefaultAuditBodyBytes of the body. The format is either "json",
+// producing one JSON object per line, or "text", producing space-separated key=value pairs. The body
+// is captured, by means of a TeeReader, only as the passed Responder reads it, so it remains
+// available to other decorators.
+func ByAuditingResponse(w io.Writer, format string) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if format != "json" && format != "text" {
+				return NewError("autorest", "ByAuditingResponse", "Unsupported audit format %s", format)
+			}
+			if resp == nil {
+				return r.Respond(resp)
+			}
+			start := DefaultClock.Now()
+			if resp.Request != nil {
+				if t := StartTimeFromContext(resp.Request.Context()); !t.IsZero() {
+					start = t
+				}
+			}
+			snippet := &prefixBuffer{max: DefaultAuditBodyBytes}
+			if resp.Body != nil {
+				resp.Body = readCloser{Reader: io.TeeReader(resp.Body, snippet), Closer: resp.Body}
+			}
+			err := r.Respond(resp)
+
+			entry := struct {
+				Timestamp time.Time   `json:"timestamp"`
+				Method    string      `json:"method"`
+				URL       string      `json:"url"`
+				Status    int         `json:"status"`
+				Latency   string      `json:"latency"`
+				Headers   http.Header `json:"headers"`
+				Body      string      `json:"body"`
+			}{
+				Timestamp: DefaultClock.Now().UTC(),
+				Status:    resp.StatusCode,
+				Latency:   DefaultClock.Now().Sub(start).String(),
+				Headers:   resp.Header,
+				Body:      string(snippet.b),
+			}
+			if resp.Request != nil {
+				entry.Method = resp.Request.Method
+				if resp.Request.URL != nil {
+					entry.URL = resp.Request.URL.String()
+				}
+			}
+
+			var werr error
+			if format == "json" {
+				werr = json.NewEncoder(w).Encode(entry)
+			} else {
+				_, werr = fmt.Fprintf(w, "timestamp=%s method=%s url=%q status=%d latency=%s headers=%q body=%q\n",
+					entry.Timestamp.Format(time.RFC3339Nano),
+					entry.Method,
+					entry.URL,
+					entry.Status,
+					entry.Latency,
+					fmt.Sprint(entry.Headers),
+					entry.Body)
+			}
+			if err == nil && werr != nil {
+				err = NewErrorWithError(werr, "autorest", "ByAuditingResponse", resp.StatusCode, "Failure writing audit entry")
+			}
+			return err
+		})
+	}
+}
+
+// ByRecordingRetryCount returns a RespondDecorator that, before invoking the passed Responder,
+// increments the int pointed to by dest. Sharing a Responder built with it across the attempts made
+// for a single request (e.g., by retry logic) thus counts those attempts. It also adds to dest the
+// requests re-sent by the retry decorators of this package (e.g., ByRetryingOnStatusCode,
+// ByRetryAfterRespecting, and ByWaitingForEventualConsistency) that it wraps; to count these, it
+// replaces the response's http.Request with one whose context carries the counter. Responders built
+// from it are not safe for concurrent use.
+func ByRecordingRetryCount(dest *int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			*dest++
+			if resp == nil || resp.Request == nil {
+				return r.Respond(resp)
+			}
+			var resent int32
+			resp.Request = resp.Request.WithContext(context.WithValue(resp.Request.Context(), contextResendCountKey, &resent))
+			err := r.Respond(resp)
+			*dest += int(atomic.LoadInt32(&resent))
+			return err
+		})
+	}
+}
+
+// ByRateLimitingResponses returns a RespondDecorator that, before invoking the passed Responder,
+// blocks as needed to pass along no more than rps responses per second. All Responders created
+// from the returned decorator share the same limit.
+func ByRateLimitingResponses(rps float64) RespondDecorator {
+	return ByRateLimitingResponsesWithLimiter(NewRateLimiter(rps))
+}
+
+// ByRateLimitingResponsesWithLimiter returns a RespondDecorator that, before invoking the passed
+// Responder, waits on the supplied RateLimiter. Sharing a RateLimiter among decorators applies a
+// single limit across multiple pipelines. Waiting honours the context of the response's
+// http.Request, if any.
+func ByRateLimitingResponsesWithLimiter(limiter RateLimiter) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			ctx := context.Background()
+			if resp != nil && resp.Request != nil {
+				ctx = resp.Request.Context()
+			}
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// ETagCache is the interface that stores and retrieves responses for ByETagCaching.
+//
+// Get returns the http.Response stored for the passed key and whether one exists. Set stores the
+// passed http.Response under the passed key. The responses ByETagCaching stores carry a
+// *BufferedBody, so that they may be replayed any number of times; a response whose Body lacks a
+// Bytes method is read once and stored again with a *BufferedBody. Implementations shared among
+// go-routines must be safe for concurrent use.
+type ETagCache interface {
+	Get(key string) (*http.Response, bool)
+	Set(key string, resp *http.Response)
+}
+
+// isReplayedHeader reports whether ByETagCaching copies the passed header from an HTTP 304 Not
+// Modified response onto the replayed cached response. Entity headers (i.e., Content-*), which
+// describe the empty 304 body rather than the cached one, and hop-by-hop headers are not copied.
+func isReplayedHeader(k string) bool {
+	if strings.HasPrefix(k, "Content-") {
+		return false
+	}
+	switch k {
+	case "Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Proxy-Connection",
+		"Te", "Trailer", "Transfer-Encoding", "Upgrade":
+		return false
+	}
+	return true
+}
+
+// ByETagCaching returns a RespondDecorator that caches, keyed by request URL, HTTP 200 responses
+// carrying an ETag header and replays them for HTTP 304 Not Modified responses. When replaying, it
+// replaces the status and body of the passed http.Response with those of the cached response and
+// updates the cached headers with the end-to-end headers (e.g., ETag, Cache-Control, Date) of the
+// 304 response, so that subsequent decorators see a complete HTTP 200 response. The entity headers
+// (e.g., Content-Length, Content-Encoding) of the cached response are kept. Sending the conditional
+// request (i.e., setting If-None-Match) remains the responsibility of the caller.
+func ByETagCaching(cache ETagCache) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || resp.Request == nil || resp.Request.URL == nil {
+				return r.Respond(resp)
+			}
+			key := resp.Request.URL.String()
+
+			switch {
+			case resp.StatusCode == http.StatusOK && resp.Header.Get(headerETag) != "" && resp.Body != nil:
+				b, err := ioutil.ReadAll(resp.Body)
+				resp.Body = readCloser{Reader: bytes.NewReader(b), Closer: resp.Body}
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByETagCaching", resp.StatusCode, "Failure reading response body")
+				}
+				c := *resp
+				c.Header = resp.Header.Clone()
+				c.ContentLength = int64(len(b))
+				c.Body = &BufferedBody{Reader: bytes.NewReader(b), b: b}
+				cache.Set(key, &c)
+
+			case resp.StatusCode == http.StatusNotModified:
+				c, ok := cache.Get(key)
+				if !ok || c == nil {
+					break
+				}
+				var b []byte
+				if bb, ok := c.Body.(interface{ Bytes() []byte }); ok {
+					b = bb.Bytes()
+				} else if c.Body != nil {
+					var err error
+					b, err = ioutil.ReadAll(c.Body)
+					c.Body.Close()
+					if err != nil {
+						return NewErrorWithError(err, "autorest", "ByETagCaching", resp.StatusCode, "Failure reading cached response body")
+					}
+					sc := *c
+					sc.Body = &BufferedBody{Reader: bytes.NewReader(b), b: b}
+					cache.Set(key, &sc)
+				}
+				h := c.Header.Clone()
+				if h == nil {
+					h = make(http.Header)
+				}
+				for k, v := range resp.Header {
+					if isReplayedHeader(k) {
+						h[k] = v
+					}
+				}
+				if resp.Body != nil {
+					resp.Body.Close()
+				}
+				resp.Status = c.Status
+				resp.StatusCode = c.StatusCode
+				resp.Header = h
+				resp.ContentLength = int64(len(b))
+				resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// WithErrorUnlessStatusCode returns a RespondDecorator that emits an error unless the response
+// StatusCode is among the set passed. The emitted error is a *DetailedError whose Response is the
+// failing http.Response (see ResponseFromError). Since these are artificial errors, the response
+// body may still require closing.
+func WithErrorUnlessStatusCode(codes ...int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err == nil && !ResponseHasStatusCode(resp, codes...) {
+				de := NewDetailedError(nil, "autorest", "WithErrorUnlessStatusCode", resp.StatusCode, "%v %v failed with %s",
+					resp.Request.Method,
+					resp.Request.URL,
+					resp.Status)
+				de.Response = resp
+				err = de
+			}
+			return err
+		})
+	}
+}
+
+// WithErrorUnlessStatusCodeFunc returns a RespondDecorator that, like WithErrorUnlessStatusCode,
+// emits an error unless the response StatusCode is among the set passed. The emitted error is that
+// returned by passing the http.Response to the supplied factory, allowing callers to map responses
+// to their own error types. Since these are artificial errors, the response body may still require
+// closing.
+func WithErrorUnlessStatusCodeFunc(factory func(*http.Response) error, codes ...int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err == nil && !ResponseHasStatusCode(resp, codes...) {
+				err = factory(resp)
+			}
+			return err
+		})
+	}
+}
+
+// WithErrorUnlessStatusCodeOrMethodIs returns a RespondDecorator that, like
+// WithErrorUnlessStatusCode, emits a *DetailedError unless the response StatusCode is among the set
+// passed. If the method of the response's http.Request is method (compared without regard to case),
+// the codes in allowedCodesForMethod are accepted as well (e.g., to treat an HTTP 404 Not Found
+// returned for a DELETE as success). Since these are artificial errors, the response body may still
+// require closing.
+func WithErrorUnlessStatusCodeOrMethodIs(method string, allowedCodesForMethod []int, codes ...int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err != nil || ResponseHasStatusCode(resp, codes...) {
+				return err
+			}
+			if resp.Request != nil && strings.EqualFold(resp.Request.Method, method) && ResponseHasStatusCode(resp, allowedCodesForMethod...) {
+				return nil
+			}
+			de := NewDetailedError(nil, "autorest", "WithErrorUnlessStatusCodeOrMethodIs", resp.StatusCode, "%s", failedResponseMessage(resp))
+			de.Response = resp
+			return de
+		})
+	}
+}
+
+// failedResponseMessage describes the passed http.Response for an error emitted because of its
+// StatusCode, naming the method and URL of its http.Request, if any.
+func failedResponseMessage(resp *http.Response) string {
+	if resp.Request == nil {
+		return fmt.Sprintf("Response failed with %s", resp.Status)
+	}
+	return fmt.Sprintf("%v %v failed with %s", resp.Request.Method, resp.Request.URL, resp.Status)
+}
+
+// WithErrorUnlessStatusCodeRange returns a RespondDecorator that emits an error unless the
+// response StatusCode falls within the inclusive range from low to high. The emitted error is a
+// *DetailedError whose Response is the failing http.Response (see ResponseFromError). Since these are
+// artificial errors, the response body may still require closing.
+func WithErrorUnlessStatusCodeRange(low, high int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err == nil && (resp.StatusCode < low || resp.StatusCode > high) {
+				de := NewDetailedError(nil, "autorest", "WithErrorUnlessStatusCodeRange", resp.StatusCode, "%s", failedResponseMessage(resp))
+				de.Response = resp
+				err = de
+			}
+			return err
+		})
+	}
+}
+
+// WithErrorUnless2XX returns a RespondDecorator that emits an error if the response StatusCode is
+// anything other than one of the HTTP 2xx codes.
+func WithErrorUnless2XX() RespondDecorator {
+	return WithErrorUnlessStatusCodeRange(200, 299)
+}
+
+// WithDetailedErrorUnlessStatusCode returns a RespondDecorator that, like
+// WithErrorUnlessStatusCode, emits an error unless the response StatusCode is among the set passed.
+// The emitted error is a *DetailedError whose ServiceError, if the response body contains an Azure
+// error object (i.e., {"error":{"code":"...","message":"..."}}), holds the parsed code and message.
+// The response body is restored after being read and may still require closing.
+func WithDetailedErrorUnlessStatusCode(codes ...int) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err == nil && !ResponseHasStatusCode(resp, codes...) {
+				de := NewDetailedError(extractServiceError(resp), "autorest", "WithDetailedErrorUnlessStatusCode", resp.StatusCode, "%v %v failed with %s",
+					resp.Request.Method,
+					resp.Request.URL,
+					resp.Status)
+				de.Response = resp
+				err = de
+			}
+			return err
+		})
+	}
+}
+
+// WithErrorUnlessOK returns a RespondDecorator that emits an error if the response StatusCode is
+// anything other than HTTP 200.
+func WithErrorUnlessOK() RespondDecorator {
+	return WithErrorUnlessStatusCode(http.StatusOK)
+}
+
+// WithErrorUnlessCreated returns a RespondDecorator that emits an error if the response StatusCode
+// is anything other than HTTP 201.
+func WithErrorUnlessCreated() RespondDecorator {
+	return WithErrorUnlessStatusCode(http.StatusCreated)
+}
+
+// WithErrorUnlessAccepted returns a RespondDecorator that emits an error if the response StatusCode
+// is anything other than HTTP 202.
+func WithErrorUnlessAccepted() RespondDecorator {
+	return WithErrorUnlessStatusCode(http.StatusAccepted)
+}
+
+// WithErrorUnlessNoContent returns a RespondDecorator that emits an error if the response
+// StatusCode is anything other than HTTP 204.
+func WithErrorUnlessNoContent() RespondDecorator {
+	return WithErrorUnlessStatusCode(http.StatusNoContent)
+}
+
+// ExtractHeader extracts all values of the specified header from the http.Response. It returns an
+// empty string slice if the passed http.Response is nil or the header does not exist. The header
+// name is matched without regard to case, so headers stored under a non-canonical key (e.g., in
+// hand-built test responses) are also found.
+func ExtractHeader(header string, resp *http.Response) []string {
+	if resp != nil && resp.Header != nil {
+		if v := resp.Header.Values(header); v != nil {
+			return v
+		}
+		for k, v := range resp.Header {
+			if strings.EqualFold(k, header) {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// ExtractHeaders returns a copy of all headers of the http.Response keyed by canonical header name.
+// Changes to the returned map do not affect the response. It returns an empty map if the passed
+// http.Response is nil or lacks headers.
+func ExtractHeaders(resp *http.Response) map[string][]string {
+	h := make(map[string][]string)
+	if resp != nil {
+		for k, v := range resp.Header {
+			k = http.CanonicalHeaderKey(k)
+			h[k] = append(h[k], v...)
+		}
+	}
+	return h
+}
+
+// ExtractHeaderValue extracts the first value of the specified header from the http.Response. It
+// returns an empty string if the passed http.Response is nil or the header does not exist.
+func ExtractHeaderValue(header string, resp *http.Response) string {
+	h := ExtractHeader(header, resp)
+	if len(h) > 0 {
+		return h[0]
+	}
+	return ""
+}
+
+// findLink returns the target URL of the first link, among the passed Link header values, whose
+// rel parameter includes the passed relation type, or the empty string if there is none.
+func findLink(values []string, rel string) string {
+	for _, v := range values {
+		for _, link := range splitLinkHeader(v, ',') {
+			link = strings.TrimSpace(link)
+			end := strings.IndexByte(link, '>')
+			if !strings.HasPrefix(link, "<") || end < 0 {
+				continue
+			}
+			for _, param := range splitLinkHeader(link[end+1:], ';') {
+				name, value, found := strings.Cut(param, "=")
+				if !found || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, t := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+					if strings.EqualFold(t, rel) {
+						return link[1:end]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// splitLinkHeader splits the passed string around each instance of sep that is outside both a
+// quoted string and a URI reference enclosed in angle brackets.
+func splitLinkHeader(s string, sep byte) []string {
+	var parts []string
+	quoted, bracketed, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' && !bracketed:
+			quoted = !quoted
+		case c == '\\' && quoted:
+			i++
+		case c == '<' && !quoted:
+			bracketed = true
+		case c == '>' && !quoted:
+			bracketed = false
+		case c == sep && !quoted && !bracketed:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// extractServiceError returns the ServiceError contained in the body of the passed http.Response
+// or nil if the body does not contain one. It restores the body after reading it.
+func extractServiceError(resp *http.Response) *ServiceError {
+	if resp.Body == nil {
+		return nil
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body = readCloser{Reader: bytes.NewReader(b), Closer: resp.Body}
+	if err != nil {
+		return nil
+	}
+	e := struct {
+		Error *ServiceError `json:"error"`
+	}{}
+	if json.Unmarshal(b, &e) != nil {
+		return nil
+	}
+	return e.Error
 }
@@ -0,0 +1,67 @@
+//go:build xtext
+// +build xtext
+
+package autorest
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ByConvertingBodyEncoding returns a RespondDecorator that, before invoking the passed Responder,
+// transcodes the response body from the fromCharset to the toCharset character encoding (e.g.,
+// "windows-1252" to "utf-8") and sets the charset parameter of the Content-Type header to
+// toCharset. If fromCharset is empty, the encoding is taken from the charset parameter of the
+// Content-Type header, falling back to UTF-8. Charset names are those of the WHATWG Encoding
+// Standard (see htmlindex.Get).
+//
+// Note: To avoid a golang.org/x/text dependency for all users, this decorator is only built when
+// the xtext build tag is set (e.g., go build -tags xtext).
+func ByConvertingBodyEncoding(fromCharset, toCharset string) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+				return r.Respond(resp)
+			}
+			mediaType, params, _ := mime.ParseMediaType(resp.Header.Get(headerContentType))
+			from := fromCharset
+			if from == "" {
+				from = params["charset"]
+			}
+			if from == "" {
+				from = "utf-8"
+			}
+			fromEncoding, err := htmlindex.Get(from)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByConvertingBodyEncoding", resp.StatusCode, "Unsupported charset %s", from)
+			}
+			toEncoding, err := htmlindex.Get(toCharset)
+			if err != nil {
+				return NewErrorWithError(err, "autorest", "ByConvertingBodyEncoding", resp.StatusCode, "Unsupported charset %s", toCharset)
+			}
+
+			resp.Body = readCloser{Reader: transcodeReader(resp.Body, fromEncoding, toEncoding), Closer: resp.Body}
+			resp.ContentLength = -1
+			resp.Header.Del(headerContentLength)
+			if mediaType != "" {
+				if params == nil {
+					params = map[string]string{}
+				}
+				params["charset"] = strings.ToLower(toCharset)
+				resp.Header.Set(headerContentType, mime.FormatMediaType(mediaType, params))
+			}
+			return r.Respond(resp)
+		})
+	}
+}
+
+// transcodeReader returns an io.Reader that decodes the content of r from the from encoding and
+// re-encodes it using the to encoding.
+func transcodeReader(r io.Reader, from, to encoding.Encoding) io.Reader {
+	return to.NewEncoder().Reader(from.NewDecoder().Reader(r))
+}
@@ -0,0 +1,78 @@
+//go:build xtext
+// +build xtext
+
+package autorest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/mocks"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func newLatin1Response(t *testing.T, s string, contentType string) *http.Response {
+	b, err := charmap.Windows1252.NewEncoder().String(s)
+	if err != nil {
+		t.Fatalf("autorest: Failed to encode the test content (%v)", err)
+	}
+	r := mocks.NewResponseWithContent(b)
+	mocks.SetResponseHeader(r, headerContentType, contentType)
+	return r
+}
+
+func TestByConvertingBodyEncoding(t *testing.T) {
+	r := newLatin1Response(t, `{"name":"Renée"}`, "application/json; charset=utf-8")
+	v := struct{ Name string }{}
+	err := Respond(r,
+		ByUnmarshallingJSON(&v),
+		ByConvertingBodyEncoding("windows-1252", "utf-8"),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByConvertingBodyEncoding failed (%v)", err)
+	}
+	if v.Name != "Ren\u00e9e" {
+		t.Errorf("autorest: ByConvertingBodyEncoding decoded %q -- expected %q", v.Name, "Ren\u00e9e")
+	}
+}
+
+func TestByConvertingBodyEncodingDetectsCharset(t *testing.T) {
+	r := newLatin1Response(t, "Ren\u00e9e", "text/plain; charset=ISO-8859-1")
+	var b []byte
+	Respond(r,
+		ByInspectingResponseWithLimit(func(resp *http.Response, c []byte) { b = c }, 100),
+		ByConvertingBodyEncoding("", "utf-8"),
+		ByClosing())
+	if string(b) != "Ren\u00e9e" {
+		t.Errorf("autorest: ByConvertingBodyEncoding produced %q -- expected %q", b, "Ren\u00e9e")
+	}
+	if ct := r.Header.Get(headerContentType); ct != "text/plain; charset=utf-8" {
+		t.Errorf("autorest: ByConvertingBodyEncoding set Content-Type to %q", ct)
+	}
+}
+
+func TestByConvertingBodyEncodingReEncodes(t *testing.T) {
+	r := mocks.NewResponseWithContent("Ren\u00e9e")
+	mocks.SetResponseHeader(r, headerContentType, "text/plain")
+	Respond(r,
+		(func() RespondDecorator {
+			return func(r Responder) Responder {
+				return ResponderFunc(func(resp *http.Response) error {
+					b, _ := ioutil.ReadAll(resp.Body)
+					if string(b) != "Ren\xe9e" {
+						t.Errorf("autorest: ByConvertingBodyEncoding produced %q -- expected %q", b, "Ren\xe9e")
+					}
+					return r.Respond(resp)
+				})
+			}
+		})(),
+		ByConvertingBodyEncoding("", "windows-1252"))
+}
+
+func TestByConvertingBodyEncodingReturnsErrorForUnknownCharset(t *testing.T) {
+	r := mocks.NewResponseWithContent("")
+	if err := Respond(r, ByConvertingBodyEncoding("klingon", "utf-8"), ByClosing()); err == nil {
+		t.Error("autorest: ByConvertingBodyEncoding failed to return an error for an unknown charset")
+	}
+}
@@ -0,0 +1,59 @@
+//go:build otel
+// +build otel
+
+package autorest
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ByTracingResponse returns a RespondDecorator that annotates the OpenTelemetry span carried by the
+// context of the response's http.Request with the response status code, content length,
+// x-ms-request-id, and x-ms-correlation-request-id. For responses whose status code indicates
+// failure (i.e., HTTP 400 or greater), it also records an error event and marks the span as failed.
+// The passed Responder runs within a child span, started from the supplied trace.Tracer, so that
+// the time spent processing the response is visible in traces. The decorator does nothing if the
+// context lacks a span.
+//
+// Note: To avoid an OpenTelemetry dependency for all users, this decorator is only built when the
+// otel build tag is set (e.g., go build -tags otel).
+func ByTracingResponse(tracer trace.Tracer) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if resp == nil || resp.Request == nil {
+				return r.Respond(resp)
+			}
+			ctx := resp.Request.Context()
+			span := trace.SpanFromContext(ctx)
+			if !span.SpanContext().IsValid() {
+				return r.Respond(resp)
+			}
+
+			span.SetAttributes(
+				attribute.Int("http.status_code", resp.StatusCode),
+				attribute.Int64("http.response_content_length", resp.ContentLength),
+				attribute.String(headerRequestID, resp.Header.Get(headerRequestID)),
+				attribute.String(headerCorrelationRequestID, resp.Header.Get(headerCorrelationRequestID)))
+			if resp.StatusCode >= http.StatusBadRequest {
+				span.RecordError(NewErrorWithStatusCode("autorest", "ByTracingResponse", resp.StatusCode, "%v %v failed with %s",
+					resp.Request.Method,
+					resp.Request.URL,
+					resp.Status))
+				span.SetStatus(codes.Error, resp.Status)
+			}
+
+			_, child := tracer.Start(ctx, "autorest.Respond")
+			defer child.End()
+			err := r.Respond(resp)
+			if err != nil {
+				child.RecordError(err)
+				child.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		})
+	}
+}
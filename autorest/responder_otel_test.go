@@ -0,0 +1,65 @@
+//go:build otel
+// +build otel
+
+package autorest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/mocks"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestByTracingResponse(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("autorest")
+	ctx, span := tracer.Start(context.Background(), "request")
+
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	r.Request = r.Request.WithContext(ctx)
+	mocks.SetResponseHeader(r, headerRequestID, "request-id")
+
+	Respond(r,
+		ByTracingResponse(tracer),
+		ByClosing())
+	span.End()
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("autorest: ByTracingResponse ended %d spans, expected 2", len(spans))
+	}
+	s := spans[1]
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range s.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["http.status_code"].AsInt64() != http.StatusServiceUnavailable {
+		t.Errorf("autorest: ByTracingResponse failed to record the status code -- received %v", attrs["http.status_code"])
+	}
+	if attrs[headerRequestID].AsString() != "request-id" {
+		t.Errorf("autorest: ByTracingResponse failed to record the request ID -- received %v", attrs[headerRequestID])
+	}
+	if s.Status().Code != codes.Error {
+		t.Errorf("autorest: ByTracingResponse failed to mark the span as failed")
+	}
+}
+
+func TestByTracingResponseIgnoresResponsesWithoutSpan(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("autorest")
+
+	err := Respond(mocks.NewResponse(),
+		ByTracingResponse(tracer),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByTracingResponse failed (%v)", err)
+	}
+	if len(sr.Ended()) != 0 {
+		t.Errorf("autorest: ByTracingResponse started a span for a response lacking one")
+	}
+}
@@ -0,0 +1,47 @@
+//go:build protobuf
+// +build protobuf
+
+package autorest
+
+import (
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	mimeTypeProtobuf    = "application/x-protobuf"
+	mimeTypeProtobufAlt = "application/protobuf"
+)
+
+// ByUnmarshallingProtobuf returns a RespondDecorator that decodes a protocol buffer message returned
+// in the response Body into the passed proto.Message. It emits an error, without reading the body,
+// if the response Content-Type is not application/x-protobuf (or application/protobuf).
+//
+// Note: To avoid a protobuf dependency for all users, this decorator is only built when the
+// protobuf build tag is set (e.g., go build -tags protobuf).
+func ByUnmarshallingProtobuf(v proto.Message) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			err := r.Respond(resp)
+			if err == nil {
+				ct := resp.Header.Get(headerContentType)
+				mt, _, _ := mime.ParseMediaType(ct)
+				if mt != mimeTypeProtobuf && mt != mimeTypeProtobufAlt {
+					return NewErrorWithStatusCode("autorest", "ByUnmarshallingProtobuf", resp.StatusCode, "Content-Type %q is not %s", ct, mimeTypeProtobuf)
+				}
+				b, err := ioutil.ReadAll(resp.Body)
+				if err != nil {
+					return NewErrorWithError(err, "autorest", "ByUnmarshallingProtobuf", resp.StatusCode, "Failure reading response body")
+				}
+				err = proto.Unmarshal(b, v)
+				if err != nil {
+					err = NewErrorWithError(err, "autorest", "ByUnmarshallingProtobuf", resp.StatusCode, "Failure decoding protobuf (%d bytes)", len(b))
+				}
+			}
+			return err
+		})
+	}
+}
@@ -0,0 +1,48 @@
+//go:build protobuf
+// +build protobuf
+
+package autorest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/mocks"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+func newProtobufResponse(t *testing.T, contentType string) *http.Response {
+	b, err := proto.Marshal(&wrappers.StringValue{Value: "Rob Pike"})
+	if err != nil {
+		t.Fatalf("autorest: Failed to encode the test message (%v)", err)
+	}
+	r := mocks.NewResponseWithContent(string(b))
+	mocks.SetResponseHeader(r, headerContentType, contentType)
+	return r
+}
+
+func TestByUnmarshallingProtobuf(t *testing.T) {
+	v := &wrappers.StringValue{}
+	r := newProtobufResponse(t, mimeTypeProtobuf)
+	err := Respond(r,
+		ByUnmarshallingProtobuf(v),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingProtobuf failed (%v)", err)
+	}
+	if v.Value != "Rob Pike" {
+		t.Errorf("autorest: ByUnmarshallingProtobuf failed to properly unmarshal")
+	}
+}
+
+func TestByUnmarshallingProtobufRejectsOtherContentTypes(t *testing.T) {
+	v := &wrappers.StringValue{}
+	r := newProtobufResponse(t, mimeTypeJSON)
+	err := Respond(r,
+		ByUnmarshallingProtobuf(v),
+		ByClosing())
+	if err == nil {
+		t.Errorf("autorest: ByUnmarshallingProtobuf failed to return an error for Content-Type %s", mimeTypeJSON)
+	}
+}
@@ -1,11 +1,37 @@
 package autorest
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/crc64"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Azure/go-autorest/autorest/mocks"
 )
@@ -91,6 +117,344 @@ func TestCreateResponderRunsDecoratorsInOrder(t *testing.T) {
 	}
 }
 
+func TestCreateResponderWithContextSetsRequestContext(t *testing.T) {
+	type key string
+	var v interface{}
+	ctx := context.WithValue(context.Background(), key("k"), "v")
+
+	d := func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			v = resp.Request.Context().Value(key("k"))
+			return r.Respond(resp)
+		})
+	}
+
+	err := CreateResponderWithContext(ctx, d).Respond(mocks.NewResponse())
+	if err != nil {
+		t.Errorf("autorest: CreateResponderWithContext failed (%v)", err)
+	}
+	if v != "v" {
+		t.Errorf("autorest: CreateResponderWithContext failed to set the request context -- received %v", v)
+	}
+}
+
+func TestCreateResponderWithContextAcceptsNilRequest(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Request = nil
+
+	err := CreateResponderWithContext(context.Background()).Respond(r)
+	if err != nil {
+		t.Errorf("autorest: CreateResponderWithContext failed (%v)", err)
+	}
+	if r.Request != nil {
+		t.Errorf("autorest: CreateResponderWithContext unexpectedly added a request")
+	}
+}
+
+func TestCreateResponderWithOptions(t *testing.T) {
+	type key string
+	var ctx context.Context
+	l := &testLogger{}
+
+	d := func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			ctx = resp.Request.Context()
+			return r.Respond(resp)
+		})
+	}
+
+	err := CreateResponderWithOptions([]ResponderOption{
+		WithContext(context.WithValue(context.Background(), key("k"), "v")),
+		WithLogger(l),
+		WithRequestID("request-id")}, d).Respond(mocks.NewResponse())
+	if err != nil {
+		t.Errorf("autorest: CreateResponderWithOptions failed (%v)", err)
+	}
+	if ctx.Value(key("k")) != "v" {
+		t.Errorf("autorest: CreateResponderWithOptions failed to apply WithContext")
+	}
+	if LoggerFromContext(ctx) != l {
+		t.Errorf("autorest: CreateResponderWithOptions failed to apply WithLogger")
+	}
+	if id := RequestIDFromContext(ctx); id != "request-id" {
+		t.Errorf("autorest: CreateResponderWithOptions failed to apply WithRequestID -- received %v", id)
+	}
+}
+
+func TestRespondWithOptions(t *testing.T) {
+	var id string
+	d := func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			id = RequestIDFromContext(resp.Request.Context())
+			return r.Respond(resp)
+		})
+	}
+
+	err := RespondWithOptions(mocks.NewResponse(), []ResponderOption{WithRequestID("request-id")}, d, ByClosing())
+	if err != nil {
+		t.Errorf("autorest: RespondWithOptions failed (%v)", err)
+	}
+	if id != "request-id" {
+		t.Errorf("autorest: RespondWithOptions failed to apply the options -- received %v", id)
+	}
+}
+
+func TestRespondWithOptionsAcceptsNullResponse(t *testing.T) {
+	err := RespondWithOptions(nil, []ResponderOption{WithRequestID("request-id")})
+	if err != nil {
+		t.Errorf("autorest: RespondWithOptions returned an unexpected error when given a null Response (%v)", err)
+	}
+}
+
+func TestLoggerFromContextHandlesMissingLogger(t *testing.T) {
+	if LoggerFromContext(context.Background()) != nil {
+		t.Errorf("autorest: LoggerFromContext returned a Logger for a context lacking one")
+	}
+}
+
+func TestRespondWithContext(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+
+	err := RespondWithContext(context.Background(), r,
+		ByUnmarshallingJSON(v),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: RespondWithContext failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: RespondWithContext failed to apply the decorators")
+	}
+}
+
+func TestRespondWithContextStopsWhenCancelled(t *testing.T) {
+	s := ""
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := func(n int) RespondDecorator {
+		return func(r Responder) Responder {
+			return ResponderFunc(func(resp *http.Response) error {
+				s += fmt.Sprintf("%d", n)
+				if n == 2 {
+					cancel()
+				}
+				return r.Respond(resp)
+			})
+		}
+	}
+
+	err := RespondWithContext(ctx, mocks.NewResponse(), d(1), d(2), d(3))
+	if err != context.Canceled {
+		t.Errorf("autorest: RespondWithContext failed to return the context error -- received %v", err)
+	}
+	if s != "32" {
+		t.Errorf("autorest: RespondWithContext continued after cancellation -- expected '32', received '%s'", s)
+	}
+}
+
+func TestRespondWithContextAcceptsNullResponse(t *testing.T) {
+	err := RespondWithContext(context.Background(), nil)
+	if err != nil {
+		t.Errorf("autorest: RespondWithContext returned an unexpected error when given a null Response (%v)", err)
+	}
+}
+
+func TestDecorateResponderFromSliceRunsDecoratorsInOrder(t *testing.T) {
+	s := ""
+
+	d := func(n int) RespondDecorator {
+		return func(r Responder) Responder {
+			return ResponderFunc(func(resp *http.Response) error {
+				err := r.Respond(resp)
+				if err == nil {
+					s += fmt.Sprintf("%d", n)
+				}
+				return err
+			})
+		}
+	}
+
+	var decorators []RespondDecorator
+	for i := 1; i <= 3; i++ {
+		decorators = append(decorators, d(i))
+	}
+
+	err := DecorateResponderFromSlice(CreateResponder(), decorators).Respond(&http.Response{})
+	if err != nil {
+		t.Errorf("autorest: DecorateResponderFromSlice failed (%v)", err)
+	}
+	if s != "123" {
+		t.Errorf("autorest: DecorateResponderFromSlice invoked decorators in an incorrect order; expected '123', received '%s'", s)
+	}
+}
+
+func TestComposeRespondDecorators(t *testing.T) {
+	var order []string
+	record := func(name string) RespondDecorator {
+		return func(r Responder) Responder {
+			return ResponderFunc(func(resp *http.Response) error {
+				err := r.Respond(resp)
+				order = append(order, name)
+				return err
+			})
+		}
+	}
+	auth := []RespondDecorator{record("a"), record("b")}
+	logging := []RespondDecorator{record("c"), nil}
+
+	decorators := ComposeRespondDecorators(auth, nil, []RespondDecorator{}, logging)
+	if len(decorators) != 3 {
+		t.Fatalf("autorest: ComposeRespondDecorators returned %d decorators, expected 3", len(decorators))
+	}
+	Respond(mocks.NewResponse(), decorators...)
+	if !reflect.DeepEqual(order, []string{"a", "b", "c"}) {
+		t.Errorf("autorest: ComposeRespondDecorators failed to preserve the order of the decorators -- received %v", order)
+	}
+}
+
+func TestComposeRespondDecoratorsDoesNotAliasGroups(t *testing.T) {
+	group := make([]RespondDecorator, 1, 2)
+	group[0] = ByClosing()
+
+	decorators := ComposeRespondDecorators(group)
+	decorators = append(decorators, ByClosing())
+	if group[:2][1] != nil {
+		t.Errorf("autorest: ComposeRespondDecorators returned a slice sharing storage with a passed group")
+	}
+}
+
+func TestNamedDecoratorWrapsErrors(t *testing.T) {
+	r := mocks.NewResponseWithContent("not json")
+	err := Respond(r,
+		NamedDecorator("ByUnmarshallingJSON", ByUnmarshallingJSON(&mocks.T{})),
+		ByClosing())
+	if err == nil || !strings.HasPrefix(err.Error(), "decorator ByUnmarshallingJSON: ") {
+		t.Errorf("autorest: NamedDecorator failed to wrap the decorator error -- received %v", err)
+	}
+}
+
+func TestNamedDecoratorPassesThroughInnerErrors(t *testing.T) {
+	var e error
+	r := mocks.NewResponse()
+	err := Respond(r,
+		withErrorRespondDecorator(&e),
+		NamedDecorator("ByClosing", ByClosing()))
+	if err != e {
+		t.Errorf("autorest: NamedDecorator attributed an error of the decorated Responder to the named decorator -- received %v", err)
+	}
+}
+
+func TestNamedDecoratorPreservesUnwrapping(t *testing.T) {
+	stop := fmt.Errorf("stop")
+	r := mocks.NewResponseWithContent("1\n")
+	err := Respond(r,
+		NamedDecorator("stream", ByUnmarshallingJSONStream(func(json.RawMessage) error { return stop })),
+		ByClosing())
+	if !errors.Is(err, stop) {
+		t.Errorf("autorest: NamedDecorator prevented unwrapping the decorator error -- received %v", err)
+	}
+}
+
+func TestDecoratorName(t *testing.T) {
+	if n := DecoratorName(NamedDecorator("closer", ByClosing())); n != "closer" {
+		t.Errorf("autorest: DecoratorName returned %q, expected %q", n, "closer")
+	}
+	if n := DecoratorName(NamedDecorator("unmarshaller", ByUnmarshallingJSON(&mocks.T{}))); n != "unmarshaller" {
+		t.Errorf("autorest: DecoratorName returned %q, expected %q", n, "unmarshaller")
+	}
+	if n := DecoratorName(ByClosing()); n != "" {
+		t.Errorf("autorest: DecoratorName returned %q for an unnamed decorator", n)
+	}
+}
+
+func TestNamedDecoratorIsRecognizedByCreateSafeResponder(t *testing.T) {
+	b := &countingCloser{Reader: strings.NewReader("")}
+	r := mocks.NewResponse()
+	r.Body = b
+	CreateSafeResponder(NamedDecorator("closer", ByClosing())).Respond(r)
+	if b.closes != 1 {
+		t.Errorf("autorest: CreateSafeResponder closed the body %d times, expected once", b.closes)
+	}
+}
+
+func TestComposeRespondDecoratorsReturnsEmptySlice(t *testing.T) {
+	if decorators := ComposeRespondDecorators(); len(decorators) != 0 {
+		t.Errorf("autorest: ComposeRespondDecorators returned %d decorators for no groups", len(decorators))
+	}
+}
+
+func TestResponderBuilderRunsDecoratorsInOrder(t *testing.T) {
+	s := ""
+
+	d := func(n int) RespondDecorator {
+		return func(r Responder) Responder {
+			return ResponderFunc(func(resp *http.Response) error {
+				err := r.Respond(resp)
+				if err == nil {
+					s += fmt.Sprintf("%d", n)
+				}
+				return err
+			})
+		}
+	}
+
+	b := NewResponderBuilder(d(1)).Add(d(2))
+	p := b.Add(d(3)).Build()
+	b.Add(d(4))
+
+	err := p.Respond(&http.Response{})
+	if err != nil {
+		t.Errorf("autorest: ResponderBuilder#Build failed (%v)", err)
+	}
+	if s != "123" {
+		t.Errorf("autorest: ResponderBuilder invoked decorators in an incorrect order; expected '123', received '%s'", s)
+	}
+}
+
+func TestCombineResponders(t *testing.T) {
+	v1 := &mocks.T{}
+	v2 := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+
+	err := CombineResponders(
+		CreateResponder(ByUnmarshallingJSON(v1)),
+		CreateResponder(ByUnmarshallingJSON(v2))).Respond(r)
+	if err != nil {
+		t.Errorf("autorest: CombineResponders failed (%v)", err)
+	}
+	if v1.Name != "Rob Pike" || v2.Name != "Rob Pike" {
+		t.Errorf("autorest: CombineResponders failed to pass the body to each Responder")
+	}
+
+	b, _ := ioutil.ReadAll(r.Body)
+	if string(b) != jsonT {
+		t.Errorf("autorest: CombineResponders failed to restore the response body -- received %q", string(b))
+	}
+}
+
+func TestCombineRespondersReturnsErrors(t *testing.T) {
+	var e1, e2 error
+	r := mocks.NewResponse()
+
+	err := CombineResponders(
+		CreateResponder(withErrorRespondDecorator(&e1)),
+		CreateResponder(),
+		CreateResponder(withErrorRespondDecorator(&e2))).Respond(r)
+	if err == nil || !strings.Contains(err.Error(), "2 of 3") {
+		t.Errorf("autorest: CombineResponders failed to return the errors (%v)", err)
+	}
+	if ae, ok := err.(Error); !ok || !strings.Contains(ae.Original().Error(), e1.Error()) {
+		t.Errorf("autorest: CombineResponders failed to include the original errors (%v)", err)
+	}
+}
+
+func TestCombineRespondersAcceptsNilResponse(t *testing.T) {
+	err := CombineResponders(CreateResponder()).Respond(nil)
+	if err != nil {
+		t.Errorf("autorest: CombineResponders returned an unexpected error for a nil response (%v)", err)
+	}
+}
+
 func TestByIgnoring(t *testing.T) {
 	r := mocks.NewResponse()
 
@@ -110,6 +474,47 @@ func TestByIgnoring(t *testing.T) {
 		ByClosing())
 }
 
+func TestByInspectingResponse(t *testing.T) {
+	var b []byte
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByInspectingResponse(func(resp *http.Response, body []byte) {
+			b = body
+		}),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByInspectingResponse failed (%v)", err)
+	}
+	if string(b) != jsonT {
+		t.Errorf("autorest: ByInspectingResponse failed to pass the body -- expected %q, received %q", jsonT, string(b))
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByInspectingResponse failed to restore the response body")
+	}
+}
+
+func TestByInspectingResponseWithLimit(t *testing.T) {
+	var b []byte
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+
+	Respond(r,
+		ByUnmarshallingJSON(v),
+		ByInspectingResponseWithLimit(func(resp *http.Response, body []byte) {
+			b = body
+		}, 5),
+		ByClosing())
+	if string(b) != jsonT[:5] {
+		t.Errorf("autorest: ByInspectingResponseWithLimit failed to limit the body -- expected %q, received %q", jsonT[:5], string(b))
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByInspectingResponseWithLimit failed to restore the complete response body")
+	}
+}
+
 func TestByClosing(t *testing.T) {
 	r := mocks.NewResponse()
 	err := Respond(r, ByClosing())
@@ -180,6 +585,91 @@ func TestByClosingClosesReturnsNestedErrors(t *testing.T) {
 	}
 }
 
+func TestByClosingWithErrorReturnsCloseError(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Body.(*mocks.Body).SetCloseError(fmt.Errorf("Faux Close Error"))
+
+	err := Respond(r,
+		ByClosingWithError())
+
+	if err == nil || !strings.Contains(err.Error(), "Faux Close Error") {
+		t.Errorf("autorest: ByClosingWithError failed to return the close error (%v)", err)
+	}
+}
+
+func TestByClosingWithErrorReturnsBothErrors(t *testing.T) {
+	var e error
+
+	r := mocks.NewResponse()
+	r.Body.(*mocks.Body).SetCloseError(fmt.Errorf("Faux Close Error"))
+
+	err := Respond(r,
+		withErrorRespondDecorator(&e),
+		ByClosingWithError())
+
+	if err == nil || !strings.Contains(err.Error(), "Faux Close Error") {
+		t.Errorf("autorest: ByClosingWithError failed to return the close error (%v)", err)
+	}
+	if ae, ok := err.(Error); !ok || !reflect.DeepEqual(ae.Original(), e) {
+		t.Errorf("autorest: ByClosingWithError failed to return the nested error (%v)", err)
+	}
+}
+
+func TestByClosingWithErrorReturnsNestedErrors(t *testing.T) {
+	var e error
+
+	r := mocks.NewResponse()
+	err := Respond(r,
+		withErrorRespondDecorator(&e),
+		ByClosingWithError())
+
+	if err == nil || !reflect.DeepEqual(e, err) {
+		t.Errorf("autorest: ByClosingWithError failed to return a nested error")
+	}
+	if r.Body.(*mocks.Body).IsOpen() {
+		t.Errorf("autorest: ByClosingWithError did not close the response body")
+	}
+}
+
+func TestByClosingOnce(t *testing.T) {
+	r := mocks.NewResponse()
+	b := r.Body.(*mocks.Body)
+	err := Respond(r,
+		ByClosingOnce())
+	if err != nil {
+		t.Errorf("autorest: ByClosingOnce failed (%v)", err)
+	}
+	if b.IsOpen() {
+		t.Errorf("autorest: ByClosingOnce did not close the response body")
+	}
+}
+
+type countingCloser struct {
+	io.Reader
+	closes int
+}
+
+func (cc *countingCloser) Close() error {
+	cc.closes++
+	return nil
+}
+
+func TestByClosingOnceClosesOnlyOnce(t *testing.T) {
+	b := &countingCloser{Reader: strings.NewReader("")}
+	r := mocks.NewResponse()
+	r.Body = b
+
+	Respond(r,
+		ByClosingOnce(),
+		ByClosingOnce(),
+		ByClosing())
+	r.Body.Close()
+
+	if b.closes != 1 {
+		t.Errorf("autorest: ByClosingOnce closed the body %d times, expected 1", b.closes)
+	}
+}
+
 func TestByClosingIfErrorAcceptsNilResponse(t *testing.T) {
 	var e error
 
@@ -232,156 +722,4007 @@ func TestByClosingIfErrorClosesIfAnErrorOccurs(t *testing.T) {
 	}
 }
 
-func TestByClosingIfErrorDoesNotClosesIfNoErrorOccurs(t *testing.T) {
+func withReturnedError(e error) RespondDecorator {
+	return func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			if err := r.Respond(resp); err != nil {
+				return err
+			}
+			return e
+		})
+	}
+}
+
+func TestByClosingIfErrorClosesIfContextIsCancelled(t *testing.T) {
 	r := mocks.NewResponse()
 	Respond(r,
+		withReturnedError(NewErrorWithError(fmt.Errorf("wrapped: %w", context.Canceled), "autorest", "Test", UndefinedStatusCode, "Cancelled")),
 		ByClosingIfError())
 
-	if !r.Body.(*mocks.Body).IsOpen() {
+	if r.Body.(*mocks.Body).IsOpen() {
+		t.Errorf("autorest: ByClosingIfError did not close the response body after a wrapped context.Canceled error")
+	}
+}
+
+func TestByClosingIfErrorClosesIfContextDeadlineIsExceeded(t *testing.T) {
+	r := mocks.NewResponse()
+	Respond(r,
+		withReturnedError(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)),
+		ByClosingIfError())
+
+	if r.Body.(*mocks.Body).IsOpen() {
+		t.Errorf("autorest: ByClosingIfError did not close the response body after a wrapped context.DeadlineExceeded error")
+	}
+}
+
+func TestByClosingIfErrorDoesNotCloseIfContextIsDoneWithoutError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := mocks.NewResponse()
+	r.Request = r.Request.WithContext(ctx)
+	Respond(r,
+		ByClosingIfError())
+
+	if !r.Body.(*mocks.Body).IsOpen() {
+		t.Errorf("autorest: ByClosingIfError closed the body of a successful response whose request context was cancelled")
+	}
+}
+
+func TestByClosingIfErrorDoesNotClosesIfNoErrorOccurs(t *testing.T) {
+	r := mocks.NewResponse()
+	Respond(r,
+		ByClosingIfError())
+
+	if !r.Body.(*mocks.Body).IsOpen() {
 		t.Errorf("autorest: ByClosingIfError closed the response body even though no error occurred")
 	}
 }
 
-func TestByUnmarhallingJSON(t *testing.T) {
-	v := &mocks.T{}
-	r := mocks.NewResponseWithContent(jsonT)
+func TestByExtractingHeader(t *testing.T) {
+	var v string
+	r := mocks.NewResponse()
+	mocks.SetResponseHeaderValues(r, mocks.TestHeader, []string{"v1", "v2"})
+
 	err := Respond(r,
-		ByUnmarshallingJSON(v),
+		ByExtractingHeader(mocks.TestHeader, &v),
 		ByClosing())
 	if err != nil {
-		t.Errorf("autorest: ByUnmarshallingJSON failed (%v)", err)
+		t.Errorf("autorest: ByExtractingHeader failed (%v)", err)
 	}
-	if v.Name != "Rob Pike" || v.Age != 42 {
-		t.Errorf("autorest: ByUnmarshallingJSON failed to properly unmarshal")
+	if v != "v1" {
+		t.Errorf("autorest: ByExtractingHeader failed to extract the header -- expected %v, received %v", "v1", v)
 	}
 }
 
-func TestByUnmarhallingJSONIncludesJSONInErrors(t *testing.T) {
+func TestByExtractingHeaderHandlesMissingHeader(t *testing.T) {
+	v := "v"
+	r := mocks.NewResponse()
+
+	Respond(r,
+		ByExtractingHeader(mocks.TestHeader, &v),
+		ByClosing())
+	if v != "" {
+		t.Errorf("autorest: ByExtractingHeader failed to handle a missing header -- received %v", v)
+	}
+}
+
+func compress(t *testing.T, s string, encodings ...string) string {
+	for _, e := range encodings {
+		var b bytes.Buffer
+		var w io.WriteCloser
+		if e == "gzip" {
+			w = gzip.NewWriter(&b)
+		} else {
+			w = zlib.NewWriter(&b)
+		}
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("autorest: Failed to compress test content (%v)", err)
+		}
+		w.Close()
+		s = b.String()
+	}
+	return s
+}
+
+func TestByDecompressingBody(t *testing.T) {
+	for _, encodings := range [][]string{{"gzip"}, {"deflate"}, {"gzip", "deflate"}, {"deflate", "gzip"}} {
+		v := &mocks.T{}
+		r := mocks.NewResponseWithContent(compress(t, jsonT, encodings...))
+		mocks.SetResponseHeader(r, "Content-Encoding", strings.Join(encodings, ", "))
+		r.ContentLength = 42
+
+		err := Respond(r,
+			ByUnmarshallingJSON(v),
+			ByDecompressingBody(),
+			ByClosing())
+		if err != nil {
+			t.Errorf("autorest: ByDecompressingBody failed for %v (%v)", encodings, err)
+		}
+		if v.Name != "Rob Pike" || v.Age != 42 {
+			t.Errorf("autorest: ByDecompressingBody failed to decompress %v", encodings)
+		}
+		if r.Header.Get("Content-Encoding") != "" || r.ContentLength != -1 {
+			t.Errorf("autorest: ByDecompressingBody failed to update the headers for %v", encodings)
+		}
+	}
+}
+
+func TestByDecompressingBodyIgnoresUncompressedBody(t *testing.T) {
 	v := &mocks.T{}
-	j := jsonT[0 : len(jsonT)-2]
-	r := mocks.NewResponseWithContent(j)
+	r := mocks.NewResponseWithContent(jsonT)
+
 	err := Respond(r,
 		ByUnmarshallingJSON(v),
+		ByDecompressingBody(),
 		ByClosing())
-	if err == nil || !strings.Contains(err.Error(), j) {
-		t.Errorf("autorest: ByUnmarshallingJSON failed to return JSON in error (%v)", err)
+	if err != nil || v.Name != "Rob Pike" {
+		t.Errorf("autorest: ByDecompressingBody modified an uncompressed body (%v)", err)
 	}
 }
 
-func TestRespondAcceptsNullResponse(t *testing.T) {
-	err := Respond(nil)
-	if err != nil {
-		t.Errorf("autorest: Respond returned an unexpected error when given a null Response (%v)", err)
+func TestByDecompressingBodyReturnsErrorForUnsupportedEncoding(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, "Content-Encoding", "br")
+
+	err := Respond(r,
+		ByDecompressingBody(),
+		ByClosing())
+	if err == nil {
+		t.Errorf("autorest: ByDecompressingBody failed to return an error for an unsupported encoding")
 	}
 }
 
-func TestWithErrorUnlessStatusCode(t *testing.T) {
+func TestByDecompressingBodyClosesOriginalBody(t *testing.T) {
+	r := mocks.NewResponseWithContent(compress(t, jsonT, "gzip"))
+	b := r.Body.(*mocks.Body)
+	mocks.SetResponseHeader(r, "Content-Encoding", "gzip")
+
+	Respond(r,
+		ByDecompressingBody(),
+		ByClosing())
+	if b.IsOpen() {
+		t.Errorf("autorest: ByDecompressingBody prevented closing the original body")
+	}
+}
+
+func TestByStoringResponse(t *testing.T) {
+	var stored *http.Response
 	r := mocks.NewResponse()
-	r.Request = mocks.NewRequest()
-	r.Status = "400 BadRequest"
-	r.StatusCode = http.StatusBadRequest
 
 	err := Respond(r,
-		WithErrorUnlessStatusCode(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError),
-		ByClosingIfError())
+		ByStoringResponse(&stored),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByStoringResponse failed (%v)", err)
+	}
+	if stored != r {
+		t.Errorf("autorest: ByStoringResponse failed to store the response")
+	}
+}
+
+func TestByLimitingBodySize(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
 
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByLimitingBodySize(int64(len(jsonT))),
+		ByClosing())
 	if err != nil {
-		t.Errorf("autorest: WithErrorUnlessStatusCode returned an error (%v) for an acceptable status code (%s)", err, r.Status)
+		t.Errorf("autorest: ByLimitingBodySize failed for a body within the limit (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByLimitingBodySize interfered with reading a body within the limit")
 	}
 }
 
-func TestWithErrorUnlessStatusCodeEmitsErrorForUnacceptableStatusCode(t *testing.T) {
-	r := mocks.NewResponse()
-	r.Request = mocks.NewRequest()
-	r.Status = "400 BadRequest"
-	r.StatusCode = http.StatusBadRequest
+func TestByLimitingBodySizeReturnsErrorIfBodyIsTooLarge(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
+	b := r.Body.(*mocks.Body)
 
 	err := Respond(r,
-		WithErrorUnlessStatusCode(http.StatusOK, http.StatusUnauthorized, http.StatusInternalServerError),
-		ByClosingIfError())
+		ByUnmarshallingJSON(&mocks.T{}),
+		ByLimitingBodySize(int64(len(jsonT)-1)),
+		ByClosing())
+	if ae, ok := err.(Error); !ok || ae.Original() != ErrResponseBodyTooLarge {
+		t.Errorf("autorest: ByLimitingBodySize failed to return ErrResponseBodyTooLarge -- received %v", err)
+	}
+	if b.IsOpen() {
+		t.Errorf("autorest: ByLimitingBodySize prevented closing the response body")
+	}
+}
 
-	if err == nil {
-		t.Errorf("autorest: WithErrorUnlessStatusCode failed to return an error for an unacceptable status code (%s)", r.Status)
+func TestByBufferingBody(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	b := r.Body.(*mocks.Body)
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByBufferingBody(int64(len(jsonT))),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByBufferingBody failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByBufferingBody prevented subsequent decorators from reading the body")
+	}
+	if b.IsOpen() {
+		t.Errorf("autorest: ByBufferingBody failed to close the original body")
+	}
+	if bb, ok := r.Body.(*BufferedBody); !ok || string(bb.Bytes()) != jsonT {
+		t.Errorf("autorest: ByBufferingBody failed to replace the body with a *BufferedBody")
 	}
 }
 
-func TestWithErrorUnlessOK(t *testing.T) {
-	r := mocks.NewResponse()
-	r.Request = mocks.NewRequest()
+func TestByBufferingBodyAllowsConcurrentReaders(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByBufferingBody(1024),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByBufferingBody failed (%v)", err)
+	}
+	bb := r.Body.(*BufferedBody)
+
+	var wg sync.WaitGroup
+	results := make([]string, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rc := bb.NewReader()
+			defer rc.Close()
+			b, _ := ioutil.ReadAll(rc)
+			results[i] = string(b)
+		}(i)
+	}
+	wg.Wait()
+	for i, s := range results {
+		if s != jsonT {
+			t.Errorf("autorest: ByBufferingBody reader %d read %q, expected %q", i, s, jsonT)
+		}
+	}
+}
 
+func TestByBufferingBodyReturnsErrorIfBodyIsTooLarge(t *testing.T) {
+	mi := &mockInspector{}
+	r := mocks.NewResponseWithContent(jsonT)
+	b := r.Body.(*mocks.Body)
 	err := Respond(r,
-		WithErrorUnlessOK(),
-		ByClosingIfError())
+		mi.ByInspecting(),
+		ByBufferingBody(int64(len(jsonT)-1)))
+	if ae, ok := err.(Error); !ok || ae.Original() != ErrResponseBodyTooLarge {
+		t.Errorf("autorest: ByBufferingBody failed to return ErrResponseBodyTooLarge -- received %v", err)
+	}
+	if mi.wasInvoked {
+		t.Errorf("autorest: ByBufferingBody invoked the passed Responder for a body that is too large")
+	}
+	if b.IsOpen() {
+		t.Errorf("autorest: ByBufferingBody failed to close a body that is too large")
+	}
+}
 
+func TestByDroppingBody(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
+	b := r.Body.(*mocks.Body)
+	err := Respond(r,
+		ByDroppingBody(),
+		ByClosing())
 	if err != nil {
-		t.Errorf("autorest: WithErrorUnlessOK returned an error for OK status code (%v)", err)
+		t.Fatalf("autorest: ByDroppingBody failed (%v)", err)
+	}
+	if b.IsOpen() {
+		t.Errorf("autorest: ByDroppingBody failed to close the original body")
+	}
+	if r.Body != http.NoBody {
+		t.Errorf("autorest: ByDroppingBody failed to replace the body with http.NoBody")
 	}
 }
 
-func TestWithErrorUnlessOKEmitsErrorIfNotOK(t *testing.T) {
-	r := mocks.NewResponse()
-	r.Request = mocks.NewRequest()
-	r.Status = "400 BadRequest"
-	r.StatusCode = http.StatusBadRequest
+func TestByDroppingBodyDrainsBodyOverNetwork(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(jsonT))
+	}))
+	defer s.Close()
+
+	var reused bool
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+		trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+		resp, err := http.DefaultClient.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+		if err != nil {
+			t.Fatalf("autorest: request failed (%v)", err)
+		}
+		if err := Respond(resp, ByDroppingBody(), ByClosing()); err != nil {
+			t.Fatalf("autorest: ByDroppingBody failed (%v)", err)
+		}
+	}
+	if !reused {
+		t.Errorf("autorest: ByDroppingBody prevented the connection from being reused")
+	}
+}
 
+func TestByComputingResponseSize(t *testing.T) {
+	var n int64
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
 	err := Respond(r,
-		WithErrorUnlessOK(),
-		ByClosingIfError())
+		ByUnmarshallingJSON(v),
+		ByComputingResponseSize(&n),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByComputingResponseSize failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" {
+		t.Errorf("autorest: ByComputingResponseSize prevented subsequent decorators from reading the body")
+	}
+	if n == 0 || n > int64(len(jsonT)) {
+		t.Errorf("autorest: ByComputingResponseSize counted %d bytes, expected at most %d", n, len(jsonT))
+	}
+}
 
-	if err == nil {
-		t.Errorf("autorest: WithErrorUnlessOK failed to return an error for a non-OK status code (%v)", err)
+func TestByComputingResponseSizeCountsReadsAfterRespond(t *testing.T) {
+	var n int64
+	r := mocks.NewResponseWithContent(jsonT)
+	if err := Respond(r, ByComputingResponseSize(&n)); err != nil {
+		t.Fatalf("autorest: ByComputingResponseSize failed (%v)", err)
+	}
+	if n != 0 {
+		t.Errorf("autorest: ByComputingResponseSize counted %d bytes before the body was read", n)
+	}
+	ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if n != int64(len(jsonT)) {
+		t.Errorf("autorest: ByComputingResponseSize counted %d bytes, expected %d", n, len(jsonT))
 	}
 }
 
-func TestExtractHeader(t *testing.T) {
-	r := mocks.NewResponse()
-	v := []string{"v1", "v2", "v3"}
-	mocks.SetResponseHeaderValues(r, mocks.TestHeader, v)
+func TestByForwardingBodyTo(t *testing.T) {
+	var b bytes.Buffer
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
 
-	if !reflect.DeepEqual(ExtractHeader(mocks.TestHeader, r), v) {
-		t.Errorf("autorest: ExtractHeader failed to retrieve the expected header -- expected [%s]%v, received [%s]%v",
-			mocks.TestHeader, v, mocks.TestHeader, ExtractHeader(mocks.TestHeader, r))
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByForwardingBodyTo(&b),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByForwardingBodyTo failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByForwardingBodyTo prevented subsequent decorators from reading the body")
+	}
+	if b.String() != jsonT {
+		t.Errorf("autorest: ByForwardingBodyTo failed to forward the body -- expected %q, received %q", jsonT, b.String())
 	}
 }
 
-func TestExtractHeaderHandlesMissingHeader(t *testing.T) {
-	var v []string
-	r := mocks.NewResponse()
+func TestByCheckingContentType(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, "Content-Type", "Application/JSON; charset=utf-8")
 
-	if !reflect.DeepEqual(ExtractHeader(mocks.TestHeader, r), v) {
-		t.Errorf("autorest: ExtractHeader failed to handle a missing header -- expected %v, received %v",
-			v, ExtractHeader(mocks.TestHeader, r))
+	err := Respond(r,
+		ByCheckingContentType("application/json"),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByCheckingContentType failed for a matching media type (%v)", err)
 	}
 }
 
-func TestExtractHeaderValue(t *testing.T) {
-	r := mocks.NewResponse()
-	v := "v1"
-	mocks.SetResponseHeader(r, mocks.TestHeader, v)
+func TestByCheckingContentTypeEmitsErrorForMismatch(t *testing.T) {
+	c := "<html>" + strings.Repeat("x", 1024) + "</html>"
+	r := mocks.NewResponseWithContent(c)
+	mocks.SetResponseHeader(r, "Content-Type", "text/html")
 
-	if ExtractHeaderValue(mocks.TestHeader, r) != v {
-		t.Errorf("autorest: ExtractHeader failed to retrieve the expected header -- expected [%s]%v, received [%s]%v",
-			mocks.TestHeader, v, mocks.TestHeader, ExtractHeaderValue(mocks.TestHeader, r))
+	err := Respond(r,
+		ByCheckingContentType("application/json"),
+		ByClosingIfError())
+	if err == nil || !strings.Contains(err.Error(), "text/html") || !strings.Contains(err.Error(), c[:512]) || strings.Contains(err.Error(), c[:513]) {
+		t.Errorf("autorest: ByCheckingContentType failed to return the expected error (%v)", err)
 	}
 }
 
-func TestExtractHeaderValueHandlesMissingHeader(t *testing.T) {
-	r := mocks.NewResponse()
-	v := ""
+func TestByCheckingContentTypeEmitsErrorForMissingContentType(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
 
-	if ExtractHeaderValue(mocks.TestHeader, r) != v {
-		t.Errorf("autorest: ExtractHeader failed to retrieve the expected header -- expected [%s]%v, received [%s]%v",
-			mocks.TestHeader, v, mocks.TestHeader, ExtractHeaderValue(mocks.TestHeader, r))
+	err := Respond(r,
+		ByCheckingContentType("application/json"),
+		ByClosingIfError())
+	if err == nil {
+		t.Errorf("autorest: ByCheckingContentType failed to return an error for a missing Content-Type")
 	}
 }
 
-func TestExtractHeaderValueRetrievesFirstValue(t *testing.T) {
-	r := mocks.NewResponse()
-	v := []string{"v1", "v2", "v3"}
-	mocks.SetResponseHeaderValues(r, mocks.TestHeader, v)
+func TestByUnmarhallingJSON(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSON failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByUnmarshallingJSON failed to properly unmarshal")
+	}
+}
 
-	if ExtractHeaderValue(mocks.TestHeader, r) != v[0] {
-		t.Errorf("autorest: ExtractHeader failed to retrieve the expected header -- expected [%s]%v, received [%s]%v",
-			mocks.TestHeader, v[0], mocks.TestHeader, ExtractHeaderValue(mocks.TestHeader, r))
+func TestByUnmarhallingJSONIncludesJSONInErrors(t *testing.T) {
+	v := &mocks.T{}
+	j := jsonT[0 : len(jsonT)-2]
+	r := mocks.NewResponseWithContent(j)
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), j) {
+		t.Errorf("autorest: ByUnmarshallingJSON failed to return JSON in error (%v)", err)
+	}
+}
+
+func TestByUnmarshallingJSONUsesNumberForInterface(t *testing.T) {
+	var v interface{}
+	r := mocks.NewResponseWithContent(`{"count":9007199254740993}`)
+	err := Respond(r,
+		ByUnmarshallingJSON(&v),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingJSON failed (%v)", err)
+	}
+	n, ok := v.(map[string]interface{})["count"].(json.Number)
+	if !ok || n.String() != "9007199254740993" {
+		t.Errorf("autorest: ByUnmarshallingJSON failed to decode a number into an interface{} as json.Number -- received %#v", v)
+	}
+}
+
+func TestByUnmarshallingJSONUsesNumberForMap(t *testing.T) {
+	var v map[string]interface{}
+	r := mocks.NewResponseWithContent(`{"count":9007199254740993}`)
+	err := Respond(r,
+		ByUnmarshallingJSON(&v),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingJSON failed (%v)", err)
+	}
+	if n, ok := v["count"].(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Errorf("autorest: ByUnmarshallingJSON failed to decode a number into a map as json.Number -- received %#v", v)
+	}
+}
+
+func TestByUnmarshallingJSONDoesNotUseNumberForStructs(t *testing.T) {
+	var v struct {
+		Count interface{} `json:"count"`
+	}
+	r := mocks.NewResponseWithContent(`{"count":42}`)
+	err := Respond(r,
+		ByUnmarshallingJSON(&v),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingJSON failed (%v)", err)
+	}
+	if _, ok := v.Count.(float64); !ok {
+		t.Errorf("autorest: ByUnmarshallingJSON unexpectedly changed number decoding for a typed target -- received %T", v.Count)
+	}
+}
+
+func TestByUnmarshallingJSONAllowingEmpty(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSONAllowingEmpty(v),
+		ByClosing())
+	if err != nil || v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByUnmarshallingJSONAllowingEmpty failed to decode a non-empty body (%v)", err)
+	}
+}
+
+func TestByUnmarshallingJSONAllowingEmptySkipsEmptyBody(t *testing.T) {
+	v := &mocks.T{Name: "unchanged"}
+	r := mocks.NewResponse()
+	err := Respond(r,
+		ByUnmarshallingJSONAllowingEmpty(v),
+		ByClosing())
+	if err != nil || v.Name != "unchanged" {
+		t.Errorf("autorest: ByUnmarshallingJSONAllowingEmpty failed to skip an empty body (%v, %+v)", err, v)
+	}
+}
+
+func TestByUnmarshallingJSONAllowingEmptyHonorsContentLength(t *testing.T) {
+	v := &mocks.T{Name: "unchanged"}
+	r := mocks.NewResponseWithContent("unread")
+	mocks.SetResponseHeader(r, headerContentLength, "0")
+	b := r.Body.(*mocks.Body)
+	err := Respond(r,
+		ByUnmarshallingJSONAllowingEmpty(v))
+	if err != nil || v.Name != "unchanged" {
+		t.Errorf("autorest: ByUnmarshallingJSONAllowingEmpty failed to skip a body with a zero Content-Length (%v, %+v)", err, v)
+	}
+	if c, _ := ioutil.ReadAll(b); string(c) != "unread" {
+		t.Errorf("autorest: ByUnmarshallingJSONAllowingEmpty read a body with a zero Content-Length")
+	}
+}
+
+func TestByUnmarshallingJSONAllowingEmptyReturnsDecodingErrors(t *testing.T) {
+	r := mocks.NewResponseWithContent("not json")
+	err := Respond(r,
+		ByUnmarshallingJSONAllowingEmpty(&mocks.T{}),
+		ByClosing())
+	if err == nil {
+		t.Errorf("autorest: ByUnmarshallingJSONAllowingEmpty failed to return an error for an invalid body")
+	}
+}
+
+type panickingUnmarshaler struct{}
+
+func (pu *panickingUnmarshaler) UnmarshalJSON(b []byte) error {
+	panic("unexpected shape")
+}
+
+func TestByUnmarshallingJSONSafely(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSONSafely(v),
+		ByClosing())
+	if err != nil || v.Name != "Rob Pike" {
+		t.Errorf("autorest: ByUnmarshallingJSONSafely failed to decode a valid target (%v)", err)
+	}
+}
+
+func TestByUnmarshallingJSONSafelyRejectsInvalidTargets(t *testing.T) {
+	var nilT *mocks.T
+	for _, v := range []interface{}{mocks.T{}, nilT, nil} {
+		r := mocks.NewResponseWithContent(jsonT)
+		err := Respond(r,
+			ByUnmarshallingJSONSafely(v),
+			ByClosing())
+		if err == nil {
+			t.Errorf("autorest: ByUnmarshallingJSONSafely failed to return an error for the target %T", v)
+		}
+	}
+}
+
+func TestByUnmarshallingJSONSafelyRecoversPanics(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSONSafely(&panickingUnmarshaler{}),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), "*autorest.panickingUnmarshaler") || !strings.Contains(err.Error(), "unexpected shape") {
+		t.Errorf("autorest: ByUnmarshallingJSONSafely failed to convert the panic into an error -- received %v", err)
+	}
+}
+
+func TestByUnmarhallingJSONAcceptsEmptyBody(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithStatus("204 NoContent", http.StatusNoContent)
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSON returned an error for an empty body (%v)", err)
+	}
+	if v.Name != "" || v.Age != 0 {
+		t.Errorf("autorest: ByUnmarshallingJSON modified the target for an empty body")
+	}
+}
+
+func TestByUnmarhallingJSONAcceptsNilBody(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponse()
+	r.Body = nil
+	err := Respond(r,
+		ByUnmarshallingJSON(v))
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSON returned an error for a nil body (%v)", err)
+	}
+}
+
+func TestByUnmarhallingJSONWithOptions(t *testing.T) {
+	var v interface{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSONWithOptions(&v, func(d *json.Decoder) { d.UseNumber() }),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSONWithOptions failed (%v)", err)
+	}
+	if m, ok := v.(map[string]interface{}); !ok || m["age"] != json.Number("42") {
+		t.Errorf("autorest: ByUnmarshallingJSONWithOptions failed to apply the decoder options -- received %v", v)
+	}
+}
+
+func TestByUnmarhallingJSONWithOptionsReturnsOptionErrors(t *testing.T) {
+	v := &struct {
+		Name string `json:"name"`
+	}{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSONWithOptions(v, (*json.Decoder).DisallowUnknownFields),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), "age") {
+		t.Errorf("autorest: ByUnmarshallingJSONWithOptions failed to return an error for an unknown field (%v)", err)
+	}
+}
+
+func TestByConditionallyUnmarshallingJSON(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByConditionallyUnmarshallingJSON(v, http.StatusOK, http.StatusCreated),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByConditionallyUnmarshallingJSON failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByConditionallyUnmarshallingJSON failed to properly unmarshal")
+	}
+}
+
+func TestByConditionallyUnmarshallingJSONSkipsOtherStatusCodes(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent("Unauthorized")
+	r.StatusCode = http.StatusUnauthorized
+	err := Respond(r,
+		ByConditionallyUnmarshallingJSON(v, http.StatusOK),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByConditionallyUnmarshallingJSON returned an error for a skipped status code (%v)", err)
+	}
+}
+
+func TestByUnmarshallingJSONIf(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSONIf(v, func(resp *http.Response) bool { return false }),
+		ByClosingIfError())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSONIf failed (%v)", err)
+	}
+	if v.Name != "" {
+		t.Errorf("autorest: ByUnmarshallingJSONIf decoded the body although the predicate returned false")
+	}
+	b, _ := ioutil.ReadAll(r.Body)
+	if string(b) != jsonT {
+		t.Errorf("autorest: ByUnmarshallingJSONIf consumed the body although the predicate returned false")
+	}
+}
+
+func TestByUnmarshallingJSONOrErrorDecodesSuccess(t *testing.T) {
+	v := &mocks.T{}
+	e := &ServiceError{}
+	r := mocks.NewResponseWithContent(jsonT)
+
+	err := Respond(r,
+		ByUnmarshallingJSONOrError(v, e, http.StatusBadRequest, http.StatusNotFound),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSONOrError failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByUnmarshallingJSONOrError failed to decode the success value")
+	}
+}
+
+func TestByUnmarshallingJSONOrErrorDecodesError(t *testing.T) {
+	v := &mocks.T{}
+	e := &ServiceError{}
+	r := mocks.NewResponseWithContent(`{"code": "NotFound", "message": "Not found"}`)
+	r.StatusCode = http.StatusNotFound
+
+	err := Respond(r,
+		ByUnmarshallingJSONOrError(v, e, http.StatusBadRequest, http.StatusNotFound),
+		ByClosing())
+	jbe, ok := err.(JSONBodyError)
+	if !ok || jbe.StatusCode != http.StatusNotFound || jbe.Value != e {
+		t.Errorf("autorest: ByUnmarshallingJSONOrError failed to return a JSONBodyError -- received %v", err)
+	}
+	if e.Code != "NotFound" || v.Name != "" {
+		t.Errorf("autorest: ByUnmarshallingJSONOrError failed to decode the error value")
+	}
+}
+
+func TestByUnmarhallingJSONInto(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(fmt.Sprintf(`{"Value": %s, "nextLink": "https://microsoft.com/"}`, jsonT))
+	err := Respond(r,
+		ByUnmarshallingJSONInto("value", v),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSONInto failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByUnmarshallingJSONInto failed to properly unmarshal")
+	}
+}
+
+func TestByUnmarhallingJSONIntoIgnoresMissingField(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSONInto("value", v),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSONInto failed (%v)", err)
+	}
+	if v.Name != "" || v.Age != 0 {
+		t.Errorf("autorest: ByUnmarshallingJSONInto modified the target for a missing field")
+	}
+}
+
+func TestByUnmarhallingJSONIntoIncludesJSONInErrors(t *testing.T) {
+	v := &mocks.T{}
+	j := jsonT[0 : len(jsonT)-2]
+	r := mocks.NewResponseWithContent(j)
+	err := Respond(r,
+		ByUnmarshallingJSONInto("value", v),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), j) {
+		t.Errorf("autorest: ByUnmarshallingJSONInto failed to return JSON in error (%v)", err)
+	}
+}
+
+func TestByValidatingResponseSchema(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByValidatingResponseSchema([]byte(testSchema)),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByValidatingResponseSchema failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByValidatingResponseSchema failed to restore the response body")
+	}
+}
+
+func TestByValidatingResponseSchemaReturnsViolations(t *testing.T) {
+	r := mocks.NewResponseWithContent(`{"name": 42}`)
+	err := Respond(r,
+		ByValidatingResponseSchema([]byte(testSchema)),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), "$.name: expected type string") || !strings.Contains(err.Error(), "missing required property age") {
+		t.Errorf("autorest: ByValidatingResponseSchema failed to return the violations (%v)", err)
+	}
+}
+
+func TestByValidatingResponseSchemaIncludesJSONInErrors(t *testing.T) {
+	j := jsonT[0 : len(jsonT)-2]
+	r := mocks.NewResponseWithContent(j)
+	err := Respond(r,
+		ByValidatingResponseSchema([]byte(testSchema)),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), j) {
+		t.Errorf("autorest: ByValidatingResponseSchema failed to return JSON in error (%v)", err)
+	}
+}
+
+func TestByUnmarhallingJSONList(t *testing.T) {
+	var v []mocks.T
+	var next string
+	r := mocks.NewResponseWithContent(fmt.Sprintf(`{"value": [%s, %s], "nextLink": "https://microsoft.com/?page=2"}`, jsonT, jsonT))
+	err := Respond(r,
+		ByUnmarshallingJSONList(&v, &next),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSONList failed (%v)", err)
+	}
+	if len(v) != 2 || v[1].Name != "Rob Pike" || v[1].Age != 42 {
+		t.Errorf("autorest: ByUnmarshallingJSONList failed to properly unmarshal the list -- received %v", v)
+	}
+	if next != "https://microsoft.com/?page=2" {
+		t.Errorf("autorest: ByUnmarshallingJSONList failed to unmarshal the next link -- received %v", next)
+	}
+}
+
+func TestByUnmarhallingJSONListHandlesLastPage(t *testing.T) {
+	var v []mocks.T
+	next := "https://microsoft.com/?page=2"
+	r := mocks.NewResponseWithContent(fmt.Sprintf(`{"value": [%s]}`, jsonT))
+	err := Respond(r,
+		ByUnmarshallingJSONList(&v, &next),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSONList failed (%v)", err)
+	}
+	if len(v) != 1 || next != "" {
+		t.Errorf("autorest: ByUnmarshallingJSONList failed to handle the last page -- received %v, %q", v, next)
+	}
+}
+
+func TestByUnmarhallingJSONListIncludesJSONInErrors(t *testing.T) {
+	var v []mocks.T
+	j := `{"value": {"name": "Rob Pike"}}`
+	r := mocks.NewResponseWithContent(j)
+	err := Respond(r,
+		ByUnmarshallingJSONList(&v, nil),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), j) {
+		t.Errorf("autorest: ByUnmarshallingJSONList failed to return JSON in error (%v)", err)
+	}
+}
+
+func TestByUnmarhallingXML(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(xmlT)
+	err := Respond(r,
+		ByUnmarshallingXML(v),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingXML failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByUnmarshallingXML failed to properly unmarshal")
+	}
+}
+
+func TestByUnmarshallingXMLSkipsEmptyBody(t *testing.T) {
+	v := &mocks.T{Name: "unchanged"}
+	r := mocks.NewResponse()
+	err := Respond(r,
+		ByUnmarshallingXML(v),
+		ByClosing())
+	if err != nil || v.Name != "unchanged" {
+		t.Errorf("autorest: ByUnmarshallingXML failed to skip an empty body (%v, %+v)", err, v)
+	}
+}
+
+func TestByUnmarshallingXMLHonorsContentLength(t *testing.T) {
+	v := &mocks.T{Name: "unchanged"}
+	r := mocks.NewResponseWithContent("unread")
+	mocks.SetResponseHeader(r, headerContentLength, "0")
+	b := r.Body.(*mocks.Body)
+	err := Respond(r,
+		ByUnmarshallingXML(v))
+	if err != nil || v.Name != "unchanged" {
+		t.Errorf("autorest: ByUnmarshallingXML failed to skip a body with a zero Content-Length (%v, %+v)", err, v)
+	}
+	if c, _ := ioutil.ReadAll(b); string(c) != "unread" {
+		t.Errorf("autorest: ByUnmarshallingXML read a body with a zero Content-Length")
+	}
+}
+
+func TestByUnmarhallingXMLIncludesXMLInErrors(t *testing.T) {
+	v := &mocks.T{}
+	x := xmlT[0 : len(xmlT)-2]
+	r := mocks.NewResponseWithContent(x)
+	err := Respond(r,
+		ByUnmarshallingXML(v),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), x) {
+		t.Errorf("autorest: ByUnmarshallingXML failed to return XML in error (%v)", err)
+	}
+}
+
+type testLogger struct {
+	keyvals []interface{}
+}
+
+func (tl *testLogger) Log(keyvals ...interface{}) error {
+	tl.keyvals = append(tl.keyvals, keyvals...)
+	return nil
+}
+
+func (tl *testLogger) value(key string) interface{} {
+	for i := 0; i+1 < len(tl.keyvals); i += 2 {
+		if tl.keyvals[i] == key {
+			return tl.keyvals[i+1]
+		}
+	}
+	return nil
+}
+
+func TestByLoggingResponse(t *testing.T) {
+	l := &testLogger{}
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, mocks.TestHeader, "v")
+
+	err := Respond(r,
+		ByLoggingResponse(l),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByLoggingResponse failed (%v)", err)
+	}
+	if l.value("status") != http.StatusOK {
+		t.Errorf("autorest: ByLoggingResponse failed to log the status code -- received %v", l.value("status"))
+	}
+	if h, ok := l.value("headers").(http.Header); !ok || h.Get(mocks.TestHeader) != "v" {
+		t.Errorf("autorest: ByLoggingResponse failed to log the headers -- received %v", l.value("headers"))
+	}
+	if _, ok := l.value("elapsed").(time.Duration); !ok {
+		t.Errorf("autorest: ByLoggingResponse failed to log the elapsed time")
+	}
+	if l.value("body") != nil {
+		t.Errorf("autorest: ByLoggingResponse unexpectedly logged the body")
+	}
+}
+
+func TestByLoggingResponseMeasuresFromStartTime(t *testing.T) {
+	tc := useTestClock(t)
+	l := &testLogger{}
+	r := mocks.NewResponse()
+	r.Request = r.Request.WithContext(ContextWithStartTime(context.Background(), tc.Now().Add(-time.Minute)))
+
+	Respond(r,
+		ByLoggingResponse(l),
+		ByClosing())
+	if l.value("elapsed") != time.Minute {
+		t.Errorf("autorest: ByLoggingResponse failed to measure from the request start time -- received %v", l.value("elapsed"))
+	}
+}
+
+func TestByLoggingResponseWithBody(t *testing.T) {
+	l := &testLogger{}
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+
+	err := Respond(r,
+		ByLoggingResponseWithBody(l, 10),
+		ByUnmarshallingJSON(v),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByLoggingResponseWithBody failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByLoggingResponseWithBody consumed the body needed by subsequent decorators")
+	}
+	if l.value("body") != jsonT[:10] {
+		t.Errorf("autorest: ByLoggingResponseWithBody failed to log the body -- expected %q, received %q", jsonT[:10], l.value("body"))
+	}
+}
+
+func TestBySelectiveDebugging(t *testing.T) {
+	l := &testLogger{}
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	r.StatusCode = http.StatusServiceUnavailable
+	mocks.SetResponseHeader(r, mocks.TestHeader, "v")
+
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		BySelectiveDebugging(l, http.StatusTooManyRequests, http.StatusServiceUnavailable),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: BySelectiveDebugging failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" {
+		t.Errorf("autorest: BySelectiveDebugging consumed the body needed by subsequent decorators")
+	}
+	if l.value("status") != http.StatusServiceUnavailable || l.value("body") != jsonT || l.value("method") != http.MethodGet {
+		t.Errorf("autorest: BySelectiveDebugging failed to log the response -- received %v", l.keyvals)
+	}
+	if h, ok := l.value("headers").(http.Header); !ok || h.Get(mocks.TestHeader) != "v" {
+		t.Errorf("autorest: BySelectiveDebugging failed to log the headers -- received %v", l.value("headers"))
+	}
+}
+
+func TestBySelectiveDebuggingAcceptsRequestWithoutURL(t *testing.T) {
+	l := &testLogger{}
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	r.Request.URL = nil
+
+	Respond(r,
+		BySelectiveDebugging(l, http.StatusServiceUnavailable),
+		ByClosing())
+	if l.value("status") != http.StatusServiceUnavailable || l.value("url") != nil {
+		t.Errorf("autorest: BySelectiveDebugging failed to log a response whose request lacks a URL -- received %v", l.keyvals)
+	}
+}
+
+func TestBySelectiveDebuggingIgnoresOtherStatusCodes(t *testing.T) {
+	l := &testLogger{}
+	r := mocks.NewResponseWithContent(jsonT)
+
+	err := Respond(r,
+		BySelectiveDebugging(l, http.StatusTooManyRequests, http.StatusServiceUnavailable),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: BySelectiveDebugging failed (%v)", err)
+	}
+	if len(l.keyvals) != 0 {
+		t.Errorf("autorest: BySelectiveDebugging logged a response with a status code not among the triggers")
+	}
+}
+
+func TestRespondAcceptsNullResponse(t *testing.T) {
+	err := Respond(nil)
+	if err != nil {
+		t.Errorf("autorest: Respond returned an unexpected error when given a null Response (%v)", err)
+	}
+}
+
+func newRespondAllResponses() []*http.Response {
+	return []*http.Response{
+		mocks.NewResponseWithStatus("200 OK", http.StatusOK),
+		mocks.NewResponseWithStatus("400 BadRequest", http.StatusBadRequest),
+		nil,
+		mocks.NewResponseWithStatus("204 NoContent", http.StatusNoContent),
+	}
+}
+
+func checkRespondAllErrors(t *testing.T, name string, errs []error) {
+	t.Helper()
+	if len(errs) != 4 {
+		t.Fatalf("autorest: %s returned %d errors, expected 4", name, len(errs))
+	}
+	if errs[0] != nil || errs[2] != nil || errs[3] != nil {
+		t.Errorf("autorest: %s returned errors for successful responses -- received %v", name, errs)
+	}
+	if errs[1] == nil {
+		t.Errorf("autorest: %s failed to return the error of the failing response", name)
+	}
+}
+
+func TestRespondAll(t *testing.T) {
+	responses := newRespondAllResponses()
+	errs := RespondAll(CreateResponder(WithErrorUnlessStatusCode(http.StatusOK, http.StatusNoContent), ByClosing()), responses)
+	checkRespondAllErrors(t, "RespondAll", errs)
+	for i, r := range responses {
+		if r != nil && r.Body.(*mocks.Body).IsOpen() {
+			t.Errorf("autorest: RespondAll failed to apply the Responder to response %d", i)
+		}
+	}
+}
+
+func TestRespondAllSerial(t *testing.T) {
+	var order []int
+	responses := newRespondAllResponses()
+	errs := RespondAllSerial(CreateResponder(
+		WithErrorUnlessStatusCode(http.StatusOK, http.StatusNoContent),
+		ByObservingStatusCode(func(code int) { order = append(order, code) })), responses)
+	checkRespondAllErrors(t, "RespondAllSerial", errs)
+	if !reflect.DeepEqual(order, []int{http.StatusOK, http.StatusBadRequest, http.StatusNoContent}) {
+		t.Errorf("autorest: RespondAllSerial failed to process the responses in order -- received %v", order)
+	}
+}
+
+func TestRespondAllAcceptsEmptySlice(t *testing.T) {
+	if errs := RespondAll(CreateResponder(), nil); len(errs) != 0 {
+		t.Errorf("autorest: RespondAll returned %d errors for no responses", len(errs))
+	}
+}
+
+// newResendableResponse returns a response whose request body, like that of requests with an
+// in-memory body, may be restored by means of GetBody.
+func newResendableResponse(s string, c int) *http.Response {
+	r := mocks.NewResponseWithStatus(s, c)
+	r.Request.GetBody = func() (io.ReadCloser, error) {
+		return mocks.NewBody(""), nil
+	}
+	return r
+}
+
+func TestByRetryingOnStatusCode(t *testing.T) {
+	s := mocks.NewSender()
+	s.EmitContent(jsonT)
+	v := &mocks.T{}
+
+	r := newResendableResponse("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByRetryingOnStatusCodeWithBackoff(s, 3, 0, http.StatusServiceUnavailable),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByRetryingOnStatusCode failed (%v)", err)
+	}
+	if s.Attempts() != 1 {
+		t.Errorf("autorest: ByRetryingOnStatusCode re-sent the request %d times, expected 1", s.Attempts())
+	}
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("autorest: ByRetryingOnStatusCode failed to replace the response -- received %v", r.StatusCode)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByRetryingOnStatusCode failed to pass the final response along")
+	}
+}
+
+func TestByRetryingOnStatusCodeStopsAfterMaxRetries(t *testing.T) {
+	s := mocks.NewSender()
+	s.EmitStatus("429 TooManyRequests", 429)
+
+	r := newResendableResponse("429 TooManyRequests", 429)
+	Respond(r,
+		ByRetryingOnStatusCodeWithBackoff(s, 3, 0, 429),
+		ByClosing())
+	if s.Attempts() != 3 {
+		t.Errorf("autorest: ByRetryingOnStatusCode re-sent the request %d times, expected 3", s.Attempts())
+	}
+	if r.StatusCode != 429 {
+		t.Errorf("autorest: ByRetryingOnStatusCode returned an unexpected status code -- received %v", r.StatusCode)
+	}
+}
+
+func TestByRetryingOnStatusCodeIgnoresOtherStatusCodes(t *testing.T) {
+	s := mocks.NewSender()
+
+	r := mocks.NewResponse()
+	Respond(r,
+		ByRetryingOnStatusCodeWithBackoff(s, 3, 0, http.StatusServiceUnavailable),
+		ByClosing())
+	if s.Attempts() != 0 {
+		t.Errorf("autorest: ByRetryingOnStatusCode unexpectedly re-sent the request")
+	}
+}
+
+func TestByRetryingOnStatusCodeReturnsSendErrors(t *testing.T) {
+	s := mocks.NewSender()
+	s.EmitErrors(1)
+
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	err := Respond(r,
+		ByRetryingOnStatusCodeWithBackoff(s, 3, 0, http.StatusServiceUnavailable),
+		ByClosing())
+	if err == nil {
+		t.Errorf("autorest: ByRetryingOnStatusCode failed to return the send error")
+	}
+}
+
+func TestByRetryingOnStatusCodeClosesBodyBeforeResending(t *testing.T) {
+	r := newResendableResponse("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	first := r.Body.(*mocks.Body)
+	s := SenderFunc(func(req *http.Request) (*http.Response, error) {
+		if first.IsOpen() {
+			t.Error("autorest: ByRetryingOnStatusCode re-sent the request before closing the prior response body")
+		}
+		return mocks.NewResponse(), nil
+	})
+	Respond(r,
+		ByRetryingOnStatusCodeWithBackoff(s, 1, 0, http.StatusServiceUnavailable),
+		ByClosing())
+}
+
+func TestByRetryingOnStatusCodeReturnsErrorForUnrestorableBody(t *testing.T) {
+	s := mocks.NewSender()
+
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	err := Respond(r,
+		ByRetryingOnStatusCodeWithBackoff(s, 3, 0, http.StatusServiceUnavailable),
+		ByClosing())
+	if err == nil || s.Attempts() != 0 {
+		t.Errorf("autorest: ByRetryingOnStatusCode re-sent a request whose body cannot be restored -- received %v after %d attempts", err, s.Attempts())
+	}
+}
+
+func TestByRetryingOnStatusCodeStopsWhenCancelled(t *testing.T) {
+	useTestClock(t)
+	s := mocks.NewSender()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	r.Request = r.Request.WithContext(ctx)
+	err := Respond(r,
+		ByRetryingOnStatusCode(s, 3, http.StatusServiceUnavailable),
+		ByClosing())
+	if err != context.Canceled || s.Attempts() != 0 {
+		t.Errorf("autorest: ByRetryingOnStatusCode failed to stop when cancelled -- received %v after %d attempts", err, s.Attempts())
+	}
+}
+
+func TestByRetryingOnStatusCodeIgnoresResponsesWithoutRequest(t *testing.T) {
+	s := mocks.NewSender()
+
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	r.Request = nil
+	err := Respond(r,
+		ByRetryingOnStatusCodeWithBackoff(s, 3, 0, http.StatusServiceUnavailable),
+		ByClosing())
+	if err != nil || s.Attempts() != 0 {
+		t.Errorf("autorest: ByRetryingOnStatusCode re-sent a response without a request -- received %v after %d attempts", err, s.Attempts())
+	}
+}
+
+func newRedirectResponse(code int, location string) *http.Response {
+	req, _ := http.NewRequest("PUT", mocks.TestURL, strings.NewReader("content"))
+	r := mocks.NewResponseWithStatus(fmt.Sprintf("%d %s", code, http.StatusText(code)), code)
+	r.Request = req
+	mocks.SetLocationHeader(r, location)
+	return r
+}
+
+func TestByRetryAfterRespecting(t *testing.T) {
+	s := mocks.NewSender()
+
+	r := newResendableResponse("429 TooManyRequests", http.StatusTooManyRequests)
+	mocks.SetResponseHeader(r, "Retry-After", "0")
+	err := Respond(r,
+		ByRetryAfterRespecting(s, 3),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByRetryAfterRespecting failed (%v)", err)
+	}
+	if s.Attempts() != 1 || r.StatusCode != http.StatusOK {
+		t.Errorf("autorest: ByRetryAfterRespecting failed to re-send the request -- attempts %d, status code %v", s.Attempts(), r.StatusCode)
+	}
+}
+
+func TestByRetryAfterRespectingIgnoresResponsesWithoutRetryAfter(t *testing.T) {
+	s := mocks.NewSender()
+
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	Respond(r,
+		ByRetryAfterRespecting(s, 3),
+		ByClosing())
+	if s.Attempts() != 0 {
+		t.Errorf("autorest: ByRetryAfterRespecting re-sent a request lacking a Retry-After header")
+	}
+}
+
+func TestByRetryAfterRespectingIgnoresResponsesWithoutRequest(t *testing.T) {
+	s := mocks.NewSender()
+
+	r := mocks.NewResponseWithStatus("429 TooManyRequests", http.StatusTooManyRequests)
+	mocks.SetResponseHeader(r, "Retry-After", "0")
+	r.Request = nil
+	err := Respond(r,
+		ByRetryAfterRespecting(s, 3),
+		ByClosing())
+	if err != nil || s.Attempts() != 0 {
+		t.Errorf("autorest: ByRetryAfterRespecting re-sent a response without a request -- received %v after %d attempts", err, s.Attempts())
+	}
+}
+
+func TestResendRequestReturnsErrorWithoutRequest(t *testing.T) {
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	r.Request = nil
+	if err := resendRequest(mocks.NewSender(), r, "TestResendRequest"); err == nil {
+		t.Error("autorest: resendRequest failed to return an error for a response without a request")
+	}
+}
+
+func TestResendRequestReturnsErrorWithoutResponse(t *testing.T) {
+	s := SenderFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, nil
+	})
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	if err := resendRequest(s, r, "TestResendRequest"); err == nil || r.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("autorest: resendRequest failed to return an error when the Sender returned no response -- received %v", err)
+	}
+}
+
+func TestByRetryAfterRespectingReturnsContextError(t *testing.T) {
+	s := mocks.NewSender()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	r.Request = r.Request.WithContext(ctx)
+	mocks.SetResponseHeader(r, "Retry-After", "60")
+
+	err := Respond(r,
+		ByRetryAfterRespecting(s, 3),
+		ByClosing())
+	if err != context.Canceled {
+		t.Errorf("autorest: ByRetryAfterRespecting failed to return the context error -- received %v", err)
+	}
+	if s.Attempts() != 0 {
+		t.Errorf("autorest: ByRetryAfterRespecting re-sent the request after cancellation")
+	}
+}
+
+const testAuthChallenge = `Bearer realm="https://example.azurecr.io/oauth2/token",service="example.azurecr.io",scope="repository:a:pull,push"`
+
+func newAuthChallengeResponse(challenge string) *http.Response {
+	r := mocks.NewResponseWithStatus("401 Unauthorized", http.StatusUnauthorized)
+	r.Request = mocks.NewRequestWithContent("content")
+	r.Request.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader("content")), nil
+	}
+	mocks.SetResponseHeader(r, headerWWWAuthenticate, challenge)
+	return r
+}
+
+func TestByFollowingAuthChallenge(t *testing.T) {
+	var realm, service, scope, auth, body string
+	s := SenderFunc(func(r *http.Request) (*http.Response, error) {
+		auth = r.Header.Get(headerAuthorization)
+		c, _ := ioutil.ReadAll(r.Body)
+		body = string(c)
+		resp := mocks.NewResponse()
+		resp.Request = r
+		return resp, nil
+	})
+
+	r := newAuthChallengeResponse(testAuthChallenge)
+	original := r.Body.(*mocks.Body)
+	err := Respond(r,
+		ByFollowingAuthChallenge(s, func(rl, sv, sc string) (string, error) {
+			realm, service, scope = rl, sv, sc
+			return "token", nil
+		}),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByFollowingAuthChallenge failed (%v)", err)
+	}
+	if realm != "https://example.azurecr.io/oauth2/token" || service != "example.azurecr.io" || scope != "repository:a:pull,push" {
+		t.Errorf("autorest: ByFollowingAuthChallenge parsed the challenge as realm=%q service=%q scope=%q", realm, service, scope)
+	}
+	if auth != "Bearer token" || body != "content" {
+		t.Errorf("autorest: ByFollowingAuthChallenge re-sent the request with Authorization %q and body %q", auth, body)
+	}
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("autorest: ByFollowingAuthChallenge failed to replace the response -- received %v", r.StatusCode)
+	}
+	if original.IsOpen() {
+		t.Errorf("autorest: ByFollowingAuthChallenge failed to close the body of the challenged response")
+	}
+}
+
+func TestByFollowingAuthChallengeIgnoresOtherResponses(t *testing.T) {
+	responses := []*http.Response{
+		mocks.NewResponse(),
+		newAuthChallengeResponse(`Basic realm="example"`),
+	}
+	for _, r := range responses {
+		s := mocks.NewSender()
+		err := Respond(r,
+			ByFollowingAuthChallenge(s, func(string, string, string) (string, error) {
+				t.Error("autorest: ByFollowingAuthChallenge unexpectedly requested a token")
+				return "", nil
+			}),
+			ByClosing())
+		if err != nil || s.Attempts() != 0 {
+			t.Errorf("autorest: ByFollowingAuthChallenge unexpectedly re-sent the request for status %v (%v)", r.StatusCode, err)
+		}
+	}
+}
+
+func TestByFollowingAuthChallengeReturnsTokenProviderError(t *testing.T) {
+	s := mocks.NewSender()
+	r := newAuthChallengeResponse(testAuthChallenge)
+	err := Respond(r,
+		ByFollowingAuthChallenge(s, func(string, string, string) (string, error) {
+			return "", fmt.Errorf("no token")
+		}),
+		ByClosingIfError())
+	if err == nil || s.Attempts() != 0 {
+		t.Errorf("autorest: ByFollowingAuthChallenge failed to return the token provider error (%v)", err)
+	}
+}
+
+func TestByRedirectFollowing(t *testing.T) {
+	var u, b, m string
+	s := SenderFunc(func(r *http.Request) (*http.Response, error) {
+		u = r.URL.String()
+		m = r.Method
+		c, _ := ioutil.ReadAll(r.Body)
+		b = string(c)
+		resp := mocks.NewResponse()
+		resp.Request = r
+		return resp, nil
+	})
+
+	r := newRedirectResponse(http.StatusTemporaryRedirect, "/d")
+	err := Respond(r,
+		ByRedirectFollowing(s, 3),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByRedirectFollowing failed (%v)", err)
+	}
+	if u != "https://microsoft.com/d" || m != "PUT" || b != "content" {
+		t.Errorf("autorest: ByRedirectFollowing failed to re-send the request -- received %s %s with body %q", m, u, b)
+	}
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("autorest: ByRedirectFollowing failed to replace the response -- received %v", r.StatusCode)
+	}
+}
+
+func TestByRedirectFollowingStopsAfterMaxRedirects(t *testing.T) {
+	attempts := 0
+	s := SenderFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		return newRedirectResponse(http.StatusPermanentRedirect, "/d"), nil
+	})
+
+	r := newRedirectResponse(http.StatusPermanentRedirect, "/d")
+	Respond(r,
+		ByRedirectFollowing(s, 2),
+		ByClosing())
+	if attempts != 2 {
+		t.Errorf("autorest: ByRedirectFollowing followed %d redirects, expected 2", attempts)
+	}
+}
+
+func TestByRedirectFollowingIgnoresOtherStatusCodes(t *testing.T) {
+	s := mocks.NewSender()
+
+	r := mocks.NewResponseWithStatus("302 Found", http.StatusFound)
+	Respond(r,
+		ByRedirectFollowing(s, 3),
+		ByClosing())
+	if s.Attempts() != 0 {
+		t.Errorf("autorest: ByRedirectFollowing unexpectedly followed a %s response", r.Status)
+	}
+}
+
+func TestByRedirectFollowingReturnsErrorIfLocationIsMissing(t *testing.T) {
+	s := mocks.NewSender()
+
+	r := newRedirectResponse(http.StatusTemporaryRedirect, "")
+	err := Respond(r,
+		ByRedirectFollowing(s, 3),
+		ByClosing())
+	if err == nil {
+		t.Errorf("autorest: ByRedirectFollowing failed to return an error for a missing Location header")
+	}
+}
+
+func TestByRedirectFollowingReturnsErrorWithoutRequest(t *testing.T) {
+	s := mocks.NewSender()
+
+	r := newRedirectResponse(http.StatusTemporaryRedirect, mocks.TestURL)
+	r.Request = nil
+	err := Respond(r,
+		ByRedirectFollowing(s, 3),
+		ByClosing())
+	if err == nil || s.Attempts() != 0 {
+		t.Errorf("autorest: ByRedirectFollowing failed to return an error for a response without a request")
+	}
+}
+
+func TestByRedirectFollowingReturnsErrorWithoutResponse(t *testing.T) {
+	s := SenderFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, nil
+	})
+
+	r := newRedirectResponse(http.StatusTemporaryRedirect, mocks.TestURL)
+	err := Respond(r,
+		ByRedirectFollowing(s, 3),
+		ByClosing())
+	if err == nil || r.StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("autorest: ByRedirectFollowing failed to return an error when the Sender returned no response")
+	}
+}
+
+func TestByMeasuringLatency(t *testing.T) {
+	var d time.Duration
+	r := mocks.NewResponse()
+
+	Respond(r,
+		(func() RespondDecorator {
+			return func(r Responder) Responder {
+				return ResponderFunc(func(resp *http.Response) error {
+					time.Sleep(10 * time.Millisecond)
+					return r.Respond(resp)
+				})
+			}
+		})(),
+		ByMeasuringLatency(&d),
+		ByClosing())
+	if d < 10*time.Millisecond {
+		t.Errorf("autorest: ByMeasuringLatency failed to include inner decorators -- received %v", d)
+	}
+}
+
+func TestByMeasuringLatencyHonoursContextStartTime(t *testing.T) {
+	var d time.Duration
+	r := mocks.NewResponse()
+	r.Request = r.Request.WithContext(ContextWithStartTime(context.Background(), time.Now().Add(-time.Hour)))
+
+	Respond(r,
+		ByMeasuringLatency(&d),
+		ByClosing())
+	if d < time.Hour {
+		t.Errorf("autorest: ByMeasuringLatency failed to honour the context start time -- received %v", d)
+	}
+}
+
+func TestStartTimeFromContextHandlesMissingStartTime(t *testing.T) {
+	if !StartTimeFromContext(context.Background()).IsZero() {
+		t.Errorf("autorest: StartTimeFromContext returned a start time for a context lacking one")
+	}
+}
+
+func TestByRateLimitingResponses(t *testing.T) {
+	p := CreateResponder(ByRateLimitingResponses(100))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		p.Respond(mocks.NewResponse())
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("autorest: ByRateLimitingResponses failed to limit responses -- three responses took %v", d)
+	}
+}
+
+func TestByRateLimitingResponsesWithLimiterSharesLimiter(t *testing.T) {
+	l := NewRateLimiter(100)
+	p1 := CreateResponder(ByRateLimitingResponsesWithLimiter(l))
+	p2 := CreateResponder(ByRateLimitingResponsesWithLimiter(l))
+
+	start := time.Now()
+	p1.Respond(mocks.NewResponse())
+	p2.Respond(mocks.NewResponse())
+	p1.Respond(mocks.NewResponse())
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("autorest: ByRateLimitingResponsesWithLimiter failed to share the limiter -- three responses took %v", d)
+	}
+}
+
+func TestByRateLimitingResponsesReturnsContextError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l := NewRateLimiter(0.001)
+	l.Wait(context.Background())
+
+	r := mocks.NewResponse()
+	r.Request = r.Request.WithContext(ctx)
+	err := Respond(r,
+		ByRateLimitingResponsesWithLimiter(l))
+	if err != context.Canceled {
+		t.Errorf("autorest: ByRateLimitingResponsesWithLimiter failed to return the context error -- received %v", err)
+	}
+}
+
+type testETagCache map[string]*http.Response
+
+func (c testETagCache) Get(key string) (*http.Response, bool) {
+	resp, ok := c[key]
+	return resp, ok
+}
+
+func (c testETagCache) Set(key string, resp *http.Response) {
+	c[key] = resp
+}
+
+func TestByETagCachingReplaysNotModifiedResponses(t *testing.T) {
+	c := testETagCache{}
+
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, "ETag", `"1"`)
+	err := Respond(r,
+		ByETagCaching(c),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByETagCaching failed (%v)", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		v := &mocks.T{}
+		r = mocks.NewResponseWithStatus("304 NotModified", http.StatusNotModified)
+		mocks.SetResponseHeader(r, mocks.TestHeader, "v")
+		err = Respond(r,
+			ByUnmarshallingJSON(v),
+			ByETagCaching(c),
+			ByClosing())
+		if err != nil {
+			t.Errorf("autorest: ByETagCaching failed (%v)", err)
+		}
+		if r.StatusCode != http.StatusOK {
+			t.Errorf("autorest: ByETagCaching failed to replace the status code -- received %v", r.StatusCode)
+		}
+		if r.Header.Get("ETag") != `"1"` || r.Header.Get(mocks.TestHeader) != "v" {
+			t.Errorf("autorest: ByETagCaching failed to merge the headers -- received %v", r.Header)
+		}
+		if v.Name != "Rob Pike" || v.Age != 42 {
+			t.Errorf("autorest: ByETagCaching failed to replay the cached body")
+		}
+	}
+}
+
+func TestByETagCachingReplaysEntriesFromAnyCache(t *testing.T) {
+	c := testETagCache{mocks.TestURL: mocks.NewResponseWithContent(jsonT)}
+	v := &mocks.T{}
+
+	r := mocks.NewResponseWithStatus("304 NotModified", http.StatusNotModified)
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByETagCaching(c),
+		ByClosing())
+	if err != nil || r.StatusCode != http.StatusOK || v.Name != "Rob Pike" {
+		t.Errorf("autorest: ByETagCaching failed to replay an entry it did not store (%v)", err)
+	}
+
+	v = &mocks.T{}
+	r = mocks.NewResponseWithStatus("304 NotModified", http.StatusNotModified)
+	err = Respond(r,
+		ByUnmarshallingJSON(v),
+		ByETagCaching(c),
+		ByClosing())
+	if err != nil || v.Name != "Rob Pike" {
+		t.Errorf("autorest: ByETagCaching failed to replay an entry it did not store a second time (%v)", err)
+	}
+}
+
+func TestByETagCachingKeepsCachedEntityHeaders(t *testing.T) {
+	c := testETagCache{}
+
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, "ETag", `"1"`)
+	mocks.SetResponseHeader(r, "Content-Length", strconv.Itoa(len(jsonT)))
+	Respond(r,
+		ByETagCaching(c),
+		ByClosing())
+
+	r = mocks.NewResponseWithStatus("304 NotModified", http.StatusNotModified)
+	mocks.SetResponseHeader(r, "ETag", `"2"`)
+	mocks.SetResponseHeader(r, "Content-Length", "0")
+	mocks.SetResponseHeader(r, "Content-Encoding", "gzip")
+	mocks.SetResponseHeader(r, "Transfer-Encoding", "chunked")
+	Respond(r,
+		ByETagCaching(c),
+		ByClosing())
+	if r.Header.Get("ETag") != `"2"` {
+		t.Errorf("autorest: ByETagCaching failed to update the cached ETag -- received %v", r.Header.Get("ETag"))
+	}
+	if r.Header.Get("Content-Length") != strconv.Itoa(len(jsonT)) || r.Header.Get("Content-Encoding") != "" || r.Header.Get("Transfer-Encoding") != "" {
+		t.Errorf("autorest: ByETagCaching replaced the cached entity headers -- received %v", r.Header)
+	}
+}
+
+func TestByETagCachingRestoresCachedBody(t *testing.T) {
+	c := testETagCache{}
+	v := &mocks.T{}
+
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, "ETag", `"1"`)
+	Respond(r,
+		ByUnmarshallingJSON(v),
+		ByETagCaching(c),
+		ByClosing())
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByETagCaching failed to restore the response body")
+	}
+}
+
+func TestByETagCachingIgnoresResponsesWithoutETag(t *testing.T) {
+	c := testETagCache{}
+
+	Respond(mocks.NewResponseWithContent(jsonT),
+		ByETagCaching(c),
+		ByClosing())
+	if len(c) != 0 {
+		t.Errorf("autorest: ByETagCaching cached a response lacking an ETag")
+	}
+}
+
+func TestByETagCachingPassesUncachedNotModifiedResponses(t *testing.T) {
+	r := mocks.NewResponseWithStatus("304 NotModified", http.StatusNotModified)
+	Respond(r,
+		ByETagCaching(testETagCache{}),
+		ByClosing())
+	if r.StatusCode != http.StatusNotModified {
+		t.Errorf("autorest: ByETagCaching modified an uncached 304 response -- received %v", r.StatusCode)
+	}
+}
+
+func TestWithErrorUnlessStatusCode(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Request = mocks.NewRequest()
+	r.Status = "400 BadRequest"
+	r.StatusCode = http.StatusBadRequest
+
+	err := Respond(r,
+		WithErrorUnlessStatusCode(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError),
+		ByClosingIfError())
+
+	if err != nil {
+		t.Errorf("autorest: WithErrorUnlessStatusCode returned an error (%v) for an acceptable status code (%s)", err, r.Status)
+	}
+}
+
+func TestWithErrorUnlessStatusCodeEmitsErrorForUnacceptableStatusCode(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Request = mocks.NewRequest()
+	r.Status = "400 BadRequest"
+	r.StatusCode = http.StatusBadRequest
+
+	err := Respond(r,
+		WithErrorUnlessStatusCode(http.StatusOK, http.StatusUnauthorized, http.StatusInternalServerError),
+		ByClosingIfError())
+
+	if err == nil {
+		t.Errorf("autorest: WithErrorUnlessStatusCode failed to return an error for an unacceptable status code (%s)", r.Status)
+	}
+}
+
+func TestWithErrorUnlessStatusCodeCarriesResponse(t *testing.T) {
+	r := mocks.NewResponseWithStatus("400 BadRequest", http.StatusBadRequest)
+	r.Request = mocks.NewRequest()
+
+	err := Respond(r,
+		WithErrorUnlessStatusCode(http.StatusOK),
+		ByClosingIfError())
+
+	de, ok := err.(*DetailedError)
+	if !ok {
+		t.Fatalf("autorest: WithErrorUnlessStatusCode returned %T, expected *DetailedError", err)
+	}
+	if de.Response != r {
+		t.Errorf("autorest: WithErrorUnlessStatusCode failed to carry the failing response on the error")
+	}
+	if de.StatusCode() != http.StatusBadRequest {
+		t.Errorf("autorest: WithErrorUnlessStatusCode set the status code to %v, expected %v", de.StatusCode(), http.StatusBadRequest)
+	}
+}
+
+type testNotFoundError struct {
+	url string
+}
+
+func (e testNotFoundError) Error() string {
+	return e.url + " not found"
+}
+
+func TestWithErrorUnlessStatusCodeFunc(t *testing.T) {
+	r := mocks.NewResponseWithStatus("404 NotFound", http.StatusNotFound)
+
+	err := Respond(r,
+		WithErrorUnlessStatusCodeFunc(func(resp *http.Response) error {
+			return testNotFoundError{url: resp.Request.URL.String()}
+		}, http.StatusOK),
+		ByClosingIfError())
+
+	if e, ok := err.(testNotFoundError); !ok || e.url != mocks.TestURL {
+		t.Errorf("autorest: WithErrorUnlessStatusCodeFunc failed to return the factory error -- received %v", err)
+	}
+}
+
+func TestWithErrorUnlessStatusCodeFuncIgnoresAcceptableStatusCode(t *testing.T) {
+	r := mocks.NewResponse()
+
+	err := Respond(r,
+		WithErrorUnlessStatusCodeFunc(func(resp *http.Response) error {
+			t.Errorf("autorest: WithErrorUnlessStatusCodeFunc invoked the factory for an acceptable status code (%s)", resp.Status)
+			return nil
+		}, http.StatusOK),
+		ByClosingIfError())
+
+	if err != nil {
+		t.Errorf("autorest: WithErrorUnlessStatusCodeFunc returned an error (%v) for an acceptable status code (%s)", err, r.Status)
+	}
+}
+
+func TestWithErrorUnlessStatusCodeOrMethodIsAcceptsCodesForMethod(t *testing.T) {
+	r := mocks.NewResponseWithStatus("404 NotFound", http.StatusNotFound)
+	r.Request = mocks.NewRequest()
+	r.Request.Method = http.MethodDelete
+
+	err := Respond(r,
+		WithErrorUnlessStatusCodeOrMethodIs("delete", []int{http.StatusNotFound}, http.StatusOK, http.StatusNoContent),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: WithErrorUnlessStatusCodeOrMethodIs returned an error (%v) for a code allowed for the method", err)
+	}
+}
+
+func TestWithErrorUnlessStatusCodeOrMethodIsRejectsCodesForOtherMethods(t *testing.T) {
+	r := mocks.NewResponseWithStatus("404 NotFound", http.StatusNotFound)
+	r.Request = mocks.NewRequest()
+	r.Request.Method = http.MethodGet
+
+	err := Respond(r,
+		WithErrorUnlessStatusCodeOrMethodIs(http.MethodDelete, []int{http.StatusNotFound}, http.StatusOK, http.StatusNoContent),
+		ByClosingIfError())
+	if de, ok := err.(*DetailedError); !ok || de.Response != r {
+		t.Errorf("autorest: WithErrorUnlessStatusCodeOrMethodIs failed to return a *DetailedError for a code not allowed for the method -- received %v", err)
+	}
+}
+
+func TestWithErrorUnlessStatusCodeOrMethodIsAcceptsCodes(t *testing.T) {
+	r := mocks.NewResponseWithStatus("200 OK", http.StatusOK)
+	r.Request = mocks.NewRequest()
+	r.Request.Method = http.MethodGet
+
+	err := Respond(r,
+		WithErrorUnlessStatusCodeOrMethodIs(http.MethodDelete, []int{http.StatusNotFound}, http.StatusOK),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: WithErrorUnlessStatusCodeOrMethodIs returned an error (%v) for an acceptable status code", err)
+	}
+}
+
+func TestWithErrorUnlessStatusCodeOrMethodIsAcceptsResponsesWithoutRequest(t *testing.T) {
+	r := mocks.NewResponseWithStatus("404 NotFound", http.StatusNotFound)
+	r.Request = nil
+
+	err := Respond(r,
+		WithErrorUnlessStatusCodeOrMethodIs(http.MethodDelete, []int{http.StatusNotFound}, http.StatusOK),
+		ByClosing())
+	if resp, ok := ResponseFromError(err); !ok || resp != r {
+		t.Errorf("autorest: WithErrorUnlessStatusCodeOrMethodIs failed to return an error for a response without a request -- received %v", err)
+	}
+}
+
+func TestWithErrorUnlessStatusCodeRange(t *testing.T) {
+	r := mocks.NewResponseWithStatus("204 NoContent", http.StatusNoContent)
+
+	err := Respond(r,
+		WithErrorUnlessStatusCodeRange(http.StatusOK, http.StatusNoContent),
+		ByClosingIfError())
+
+	if err != nil {
+		t.Errorf("autorest: WithErrorUnlessStatusCodeRange returned an error (%v) for an acceptable status code (%s)", err, r.Status)
+	}
+}
+
+func TestWithErrorUnlessStatusCodeRangeEmitsErrorForUnacceptableStatusCode(t *testing.T) {
+	r := mocks.NewResponseWithStatus("400 BadRequest", http.StatusBadRequest)
+
+	err := Respond(r,
+		WithErrorUnlessStatusCodeRange(http.StatusOK, http.StatusNoContent),
+		ByClosingIfError())
+
+	if err == nil {
+		t.Errorf("autorest: WithErrorUnlessStatusCodeRange failed to return an error for an unacceptable status code (%s)", r.Status)
+	}
+}
+
+func TestWithErrorUnlessStatusCodeRangeReturnsResponse(t *testing.T) {
+	r := mocks.NewResponseWithStatus("400 BadRequest", http.StatusBadRequest)
+
+	err := Respond(r,
+		WithErrorUnlessStatusCodeRange(http.StatusOK, http.StatusNoContent),
+		ByClosingIfError())
+
+	if resp, ok := ResponseFromError(err); !ok || resp != r {
+		t.Errorf("autorest: WithErrorUnlessStatusCodeRange failed to return the failing response in the error -- received %v", resp)
+	}
+}
+
+func TestWithErrorUnless2XX(t *testing.T) {
+	r := mocks.NewResponseWithStatus("201 Created", http.StatusCreated)
+
+	err := Respond(r,
+		WithErrorUnless2XX(),
+		ByClosingIfError())
+
+	if err != nil {
+		t.Errorf("autorest: WithErrorUnless2XX returned an error (%v) for a 2xx status code (%s)", err, r.Status)
+	}
+}
+
+func TestWithErrorUnless2XXEmitsErrorIfNot2XX(t *testing.T) {
+	r := mocks.NewResponseWithStatus("302 Found", http.StatusFound)
+
+	err := Respond(r,
+		WithErrorUnless2XX(),
+		ByClosingIfError())
+
+	if err == nil {
+		t.Errorf("autorest: WithErrorUnless2XX failed to return an error for a non-2xx status code (%s)", r.Status)
+	}
+}
+
+func TestWithDetailedErrorUnlessStatusCode(t *testing.T) {
+	r := mocks.NewResponseWithStatus("400 BadRequest", http.StatusBadRequest)
+
+	err := Respond(r,
+		WithDetailedErrorUnlessStatusCode(http.StatusBadRequest),
+		ByClosingIfError())
+
+	if err != nil {
+		t.Errorf("autorest: WithDetailedErrorUnlessStatusCode returned an error (%v) for an acceptable status code (%s)", err, r.Status)
+	}
+}
+
+func TestWithDetailedErrorUnlessStatusCodeParsesServiceError(t *testing.T) {
+	c := `{"error":{"code":"ResourceNotFound","message":"The resource was not found."}}`
+	r := mocks.NewResponseWithContent(c)
+	r.Status = "404 NotFound"
+	r.StatusCode = http.StatusNotFound
+
+	err := Respond(r,
+		WithDetailedErrorUnlessStatusCode(http.StatusOK))
+
+	de, ok := err.(*DetailedError)
+	if !ok {
+		t.Fatalf("autorest: WithDetailedErrorUnlessStatusCode failed to return a *DetailedError (%v)", err)
+	}
+	if de.StatusCode() != http.StatusNotFound {
+		t.Errorf("autorest: WithDetailedErrorUnlessStatusCode set the wrong status code -- expected %v, received %v", http.StatusNotFound, de.StatusCode())
+	}
+	if de.ServiceError == nil || de.ServiceError.Code != "ResourceNotFound" || de.ServiceError.Message != "The resource was not found." {
+		t.Errorf("autorest: WithDetailedErrorUnlessStatusCode failed to parse the service error (%v)", de.ServiceError)
+	}
+
+	b, _ := ioutil.ReadAll(r.Body)
+	if string(b) != c {
+		t.Errorf("autorest: WithDetailedErrorUnlessStatusCode failed to restore the response body -- expected %q, received %q", c, string(b))
+	}
+}
+
+func TestWithDetailedErrorUnlessStatusCodeIgnoresNonServiceErrorBody(t *testing.T) {
+	r := mocks.NewResponseWithContent("not json")
+	r.Status = "500 InternalServerError"
+	r.StatusCode = http.StatusInternalServerError
+
+	err := Respond(r,
+		WithDetailedErrorUnlessStatusCode(http.StatusOK),
+		ByClosingIfError())
+
+	de, ok := err.(*DetailedError)
+	if !ok {
+		t.Fatalf("autorest: WithDetailedErrorUnlessStatusCode failed to return a *DetailedError (%v)", err)
+	}
+	if de.ServiceError != nil {
+		t.Errorf("autorest: WithDetailedErrorUnlessStatusCode unexpectedly parsed a service error (%v)", de.ServiceError)
+	}
+}
+
+func TestWithErrorUnlessOK(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Request = mocks.NewRequest()
+
+	err := Respond(r,
+		WithErrorUnlessOK(),
+		ByClosingIfError())
+
+	if err != nil {
+		t.Errorf("autorest: WithErrorUnlessOK returned an error for OK status code (%v)", err)
+	}
+}
+
+func TestWithErrorUnlessOKEmitsErrorIfNotOK(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Request = mocks.NewRequest()
+	r.Status = "400 BadRequest"
+	r.StatusCode = http.StatusBadRequest
+
+	err := Respond(r,
+		WithErrorUnlessOK(),
+		ByClosingIfError())
+
+	if err == nil {
+		t.Errorf("autorest: WithErrorUnlessOK failed to return an error for a non-OK status code (%v)", err)
+	}
+}
+
+func TestWithErrorUnlessCreated(t *testing.T) {
+	r := mocks.NewResponseWithStatus("201 Created", http.StatusCreated)
+
+	err := Respond(r,
+		WithErrorUnlessCreated(),
+		ByClosingIfError())
+
+	if err != nil {
+		t.Errorf("autorest: WithErrorUnlessCreated returned an error for status code %s (%v)", r.Status, err)
+	}
+}
+
+func TestWithErrorUnlessCreatedEmitsErrorIfNotCreated(t *testing.T) {
+	r := mocks.NewResponse()
+
+	err := Respond(r,
+		WithErrorUnlessCreated(),
+		ByClosingIfError())
+
+	if err == nil {
+		t.Errorf("autorest: WithErrorUnlessCreated failed to return an error for status code %s", r.Status)
+	}
+}
+
+func TestWithErrorUnlessAccepted(t *testing.T) {
+	r := mocks.NewResponseWithStatus("202 Accepted", http.StatusAccepted)
+
+	err := Respond(r,
+		WithErrorUnlessAccepted(),
+		ByClosingIfError())
+
+	if err != nil {
+		t.Errorf("autorest: WithErrorUnlessAccepted returned an error for status code %s (%v)", r.Status, err)
+	}
+}
+
+func TestWithErrorUnlessAcceptedEmitsErrorIfNotAccepted(t *testing.T) {
+	r := mocks.NewResponse()
+
+	err := Respond(r,
+		WithErrorUnlessAccepted(),
+		ByClosingIfError())
+
+	if err == nil {
+		t.Errorf("autorest: WithErrorUnlessAccepted failed to return an error for status code %s", r.Status)
+	}
+}
+
+func TestWithErrorUnlessNoContent(t *testing.T) {
+	r := mocks.NewResponseWithStatus("204 NoContent", http.StatusNoContent)
+
+	err := Respond(r,
+		WithErrorUnlessNoContent(),
+		ByClosingIfError())
+
+	if err != nil {
+		t.Errorf("autorest: WithErrorUnlessNoContent returned an error for status code %s (%v)", r.Status, err)
+	}
+}
+
+func TestWithErrorUnlessNoContentEmitsErrorIfNotNoContent(t *testing.T) {
+	r := mocks.NewResponse()
+
+	err := Respond(r,
+		WithErrorUnlessNoContent(),
+		ByClosingIfError())
+
+	if err == nil {
+		t.Errorf("autorest: WithErrorUnlessNoContent failed to return an error for status code %s", r.Status)
+	}
+}
+
+func TestExtractHeader(t *testing.T) {
+	r := mocks.NewResponse()
+	v := []string{"v1", "v2", "v3"}
+	mocks.SetResponseHeaderValues(r, mocks.TestHeader, v)
+
+	if !reflect.DeepEqual(ExtractHeader(mocks.TestHeader, r), v) {
+		t.Errorf("autorest: ExtractHeader failed to retrieve the expected header -- expected [%s]%v, received [%s]%v",
+			mocks.TestHeader, v, mocks.TestHeader, ExtractHeader(mocks.TestHeader, r))
+	}
+}
+
+func TestExtractHeaderHandlesMissingHeader(t *testing.T) {
+	var v []string
+	r := mocks.NewResponse()
+
+	if !reflect.DeepEqual(ExtractHeader(mocks.TestHeader, r), v) {
+		t.Errorf("autorest: ExtractHeader failed to handle a missing header -- expected %v, received %v",
+			v, ExtractHeader(mocks.TestHeader, r))
+	}
+}
+
+func TestExtractHeaderHandlesNonCanonicalHeader(t *testing.T) {
+	r := mocks.NewResponse()
+	v := []string{"v1", "v2"}
+	r.Header = http.Header{strings.ToLower(mocks.TestHeader): v}
+
+	if !reflect.DeepEqual(ExtractHeader(mocks.TestHeader, r), v) {
+		t.Errorf("autorest: ExtractHeader failed to retrieve a non-canonical header -- expected [%s]%v, received [%s]%v",
+			mocks.TestHeader, v, mocks.TestHeader, ExtractHeader(mocks.TestHeader, r))
+	}
+}
+
+func TestExtractHeaders(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Header = http.Header{"x-lower": []string{"v1"}}
+	mocks.SetResponseHeaderValues(r, mocks.TestHeader, []string{"v2", "v3"})
+
+	h := ExtractHeaders(r)
+	expected := map[string][]string{
+		"X-Lower": {"v1"},
+		http.CanonicalHeaderKey(mocks.TestHeader): {"v2", "v3"},
+	}
+	if !reflect.DeepEqual(h, expected) {
+		t.Errorf("autorest: ExtractHeaders failed to retrieve the expected headers -- expected %v, received %v", expected, h)
+	}
+}
+
+func TestExtractHeadersReturnsCopy(t *testing.T) {
+	r := mocks.NewResponse()
+	mocks.SetResponseHeader(r, mocks.TestHeader, "v1")
+
+	h := ExtractHeaders(r)
+	h[http.CanonicalHeaderKey(mocks.TestHeader)][0] = "v2"
+	h["X-Added"] = []string{"v3"}
+
+	if r.Header.Get(mocks.TestHeader) != "v1" || r.Header.Get("X-Added") != "" {
+		t.Errorf("autorest: ExtractHeaders returned headers that share state with the response")
+	}
+}
+
+func TestExtractHeadersHandlesNilResponse(t *testing.T) {
+	if h := ExtractHeaders(nil); len(h) != 0 {
+		t.Errorf("autorest: ExtractHeaders returned headers for a nil response -- received %v", h)
+	}
+}
+
+func TestExtractHeaderValue(t *testing.T) {
+	r := mocks.NewResponse()
+	v := "v1"
+	mocks.SetResponseHeader(r, mocks.TestHeader, v)
+
+	if ExtractHeaderValue(mocks.TestHeader, r) != v {
+		t.Errorf("autorest: ExtractHeader failed to retrieve the expected header -- expected [%s]%v, received [%s]%v",
+			mocks.TestHeader, v, mocks.TestHeader, ExtractHeaderValue(mocks.TestHeader, r))
+	}
+}
+
+func TestExtractHeaderValueHandlesMissingHeader(t *testing.T) {
+	r := mocks.NewResponse()
+	v := ""
+
+	if ExtractHeaderValue(mocks.TestHeader, r) != v {
+		t.Errorf("autorest: ExtractHeader failed to retrieve the expected header -- expected [%s]%v, received [%s]%v",
+			mocks.TestHeader, v, mocks.TestHeader, ExtractHeaderValue(mocks.TestHeader, r))
+	}
+}
+
+func TestExtractHeaderValueRetrievesFirstValue(t *testing.T) {
+	r := mocks.NewResponse()
+	v := []string{"v1", "v2", "v3"}
+	mocks.SetResponseHeaderValues(r, mocks.TestHeader, v)
+
+	if ExtractHeaderValue(mocks.TestHeader, r) != v[0] {
+		t.Errorf("autorest: ExtractHeader failed to retrieve the expected header -- expected [%s]%v, received [%s]%v",
+			mocks.TestHeader, v[0], mocks.TestHeader, ExtractHeaderValue(mocks.TestHeader, r))
+	}
+}
+
+func newNextLinkSender(pages map[string]string) Sender {
+	return SenderFunc(func(r *http.Request) (*http.Response, error) {
+		resp := mocks.NewResponseWithContent(pages[r.URL.String()])
+		resp.Request = r
+		return resp, nil
+	})
+}
+
+func TestByFollowingNextLink(t *testing.T) {
+	s := newNextLinkSender(map[string]string{
+		"https://microsoft.com/a/b/c/?page=2": `{"value":[2],"nextLink":"https://microsoft.com/a/b/c/?page=3"}`,
+		"https://microsoft.com/a/b/c/?page=3": `{"value":[3]}`,
+	})
+
+	var first, rest []int
+	r := mocks.NewResponseWithContent(`{"value":[1],"nextLink":"https://microsoft.com/a/b/c/?page=2"}`)
+	err := Respond(r,
+		ByUnmarshallingJSONList(&first, nil),
+		ByFollowingNextLink(s, func(resp *http.Response) error {
+			var page []int
+			err := Respond(resp, ByUnmarshallingJSONList(&page, nil))
+			rest = append(rest, page...)
+			return err
+		}),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByFollowingNextLink failed (%v)", err)
+	}
+	if !reflect.DeepEqual(first, []int{1}) || !reflect.DeepEqual(rest, []int{2, 3}) {
+		t.Errorf("autorest: ByFollowingNextLink accumulated %v and %v -- expected [1] and [2 3]", first, rest)
+	}
+}
+
+func TestByFollowingNextLinkResolvesRelativeLinksAndCopiesHeaders(t *testing.T) {
+	var u, h string
+	s := SenderFunc(func(r *http.Request) (*http.Response, error) {
+		u = r.URL.String()
+		h = r.Header.Get(headerAuthorization)
+		resp := mocks.NewResponseWithContent(`{"value":[]}`)
+		resp.Request = r
+		return resp, nil
+	})
+
+	r := mocks.NewResponseWithContent(`{"value":[],"nextLink":"?page=2"}`)
+	r.Request.Header.Set(headerAuthorization, testAuthorizationHeader)
+	err := Respond(r,
+		ByFollowingNextLink(s, func(resp *http.Response) error { return nil }),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByFollowingNextLink failed (%v)", err)
+	}
+	if u != "https://microsoft.com/a/b/c/?page=2" || h != testAuthorizationHeader {
+		t.Errorf("autorest: ByFollowingNextLink requested %s with Authorization %q", u, h)
+	}
+}
+
+func TestByFollowingNextLinkStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := mocks.NewSender()
+
+	r := mocks.NewResponseWithContent(`{"value":[],"nextLink":"https://microsoft.com/a/b/c/?page=2"}`)
+	r.Request = r.Request.WithContext(ctx)
+	cancel()
+	err := Respond(r,
+		ByFollowingNextLink(s, func(resp *http.Response) error { return nil }),
+		ByClosing())
+	if err != context.Canceled {
+		t.Errorf("autorest: ByFollowingNextLink returned %v -- expected context.Canceled", err)
+	}
+	if s.Attempts() != 0 {
+		t.Errorf("autorest: ByFollowingNextLink sent %d requests after the context was done", s.Attempts())
+	}
+}
+
+func TestByFollowingNextLinkStopsWhenNextLinkRepeats(t *testing.T) {
+	s := newNextLinkSender(map[string]string{
+		"https://microsoft.com/a/b/c/?page=2": `{"value":[],"nextLink":"https://microsoft.com/a/b/c/?page=3"}`,
+		"https://microsoft.com/a/b/c/?page=3": `{"value":[],"nextLink":"https://microsoft.com/a/b/c/?page=2"}`,
+	})
+
+	calls := 0
+	r := mocks.NewResponseWithContent(`{"value":[],"nextLink":"https://microsoft.com/a/b/c/?page=2"}`)
+	err := Respond(r,
+		ByFollowingNextLink(s, func(resp *http.Response) error {
+			calls++
+			return nil
+		}),
+		ByClosing())
+	if err == nil || calls != 2 {
+		t.Errorf("autorest: ByFollowingNextLink failed to stop on a repeated nextLink -- %d calls, error %v", calls, err)
+	}
+}
+
+func TestByFollowingNextLinkReturnsAccumulateError(t *testing.T) {
+	s := newNextLinkSender(map[string]string{
+		"https://microsoft.com/a/b/c/?page=2": `{"value":[],"nextLink":"https://microsoft.com/a/b/c/?page=3"}`,
+	})
+
+	calls := 0
+	r := mocks.NewResponseWithContent(`{"value":[],"nextLink":"https://microsoft.com/a/b/c/?page=2"}`)
+	err := Respond(r,
+		ByFollowingNextLink(s, func(resp *http.Response) error {
+			calls++
+			return fmt.Errorf("autorest: Faux Accumulate Error")
+		}),
+		ByClosing())
+	if err == nil || calls != 1 {
+		t.Errorf("autorest: ByFollowingNextLink failed to stop on an accumulate error -- %d calls, error %v", calls, err)
+	}
+}
+
+func signTestResponse(r *http.Response, secret []byte, signedHeaders ...string) string {
+	mac := hmac.New(sha256.New, secret)
+	for _, h := range signedHeaders {
+		fmt.Fprintf(mac, "%s:%s\n", strings.ToLower(h), r.Header.Get(h))
+	}
+	mac.Write([]byte(jsonT))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestByVerifyingResponseSignature(t *testing.T) {
+	secret := []byte("secret")
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, headerRequestID, "request-id")
+	mocks.SetResponseHeader(r, "x-ms-signature", signTestResponse(r, secret, headerRequestID))
+	err := Respond(r,
+		ByUnmarshallingJSON(v),
+		ByVerifyingResponseSignature(secret, "x-ms-signature", crypto.SHA256, headerRequestID),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByVerifyingResponseSignature rejected a valid signature (%v)", err)
+	}
+	if v.Name != "Rob Pike" {
+		t.Errorf("autorest: ByVerifyingResponseSignature prevented subsequent decorators from reading the body")
+	}
+}
+
+func TestByVerifyingResponseSignatureConsumesUnreadBody(t *testing.T) {
+	secret := []byte("secret")
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, "x-ms-signature", signTestResponse(r, secret))
+	err := Respond(r,
+		ByVerifyingResponseSignature(secret, "x-ms-signature", crypto.SHA256),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByVerifyingResponseSignature failed to sign the unread body (%v)", err)
+	}
+}
+
+func TestByVerifyingResponseSignatureReturnsErrorOnMismatch(t *testing.T) {
+	secret := []byte("secret")
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, headerRequestID, "request-id")
+	mocks.SetResponseHeader(r, "x-ms-signature", signTestResponse(r, secret))
+	err := Respond(r,
+		ByVerifyingResponseSignature(secret, "x-ms-signature", crypto.SHA256, headerRequestID),
+		ByClosing())
+	if !errors.Is(err, ErrResponseSignatureMismatch) {
+		t.Errorf("autorest: ByVerifyingResponseSignature failed to return ErrResponseSignatureMismatch -- received %v", err)
+	}
+}
+
+func TestByVerifyingResponseSignatureReturnsErrorIfMissing(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByVerifyingResponseSignature([]byte("secret"), "x-ms-signature", crypto.SHA256),
+		ByClosing())
+	if !errors.Is(err, ErrResponseSignatureMismatch) {
+		t.Errorf("autorest: ByVerifyingResponseSignature failed to reject a missing signature -- received %v", err)
+	}
+}
+
+func TestByComputingResponseContentHash(t *testing.T) {
+	var hash string
+	var v map[string]interface{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSON(&v),
+		ByComputingResponseContentHash(HashSHA256, &hash),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByComputingResponseContentHash failed (%v)", err)
+	}
+	sum := sha256.Sum256([]byte(jsonT))
+	if expected := base64.StdEncoding.EncodeToString(sum[:]); hash != expected || v["name"] != "Rob Pike" {
+		t.Errorf("autorest: ByComputingResponseContentHash computed %s -- expected %s", hash, expected)
+	}
+}
+
+func TestByComputingResponseContentHashMatchesRequestContentHash(t *testing.T) {
+	for _, algorithm := range []string{HashMD5, HashSHA256, HashCRC64} {
+		var sent, received string
+		Prepare(mocks.NewRequestWithContent(jsonT), WithRequestContentHash(algorithm, &sent))
+		Respond(mocks.NewResponseWithContent(jsonT),
+			ByComputingResponseContentHash(algorithm, &received),
+			ByClosing())
+		if sent == "" || sent != received {
+			t.Errorf("autorest: ByComputingResponseContentHash(%s) computed %s -- WithRequestContentHash computed %s", algorithm, received, sent)
+		}
+	}
+}
+
+func TestByComputingResponseContentHashReturnsErrorForUnsupportedAlgorithm(t *testing.T) {
+	var hash string
+	r := mocks.NewResponseWithContent(jsonT)
+	if err := Respond(r, ByComputingResponseContentHash("SHA1", &hash), ByClosing()); err == nil || hash != "" {
+		t.Error("autorest: ByComputingResponseContentHash failed to return an error for an unsupported algorithm")
+	}
+}
+
+func TestByHashingResponseBody(t *testing.T) {
+	for _, algorithm := range []string{HashMD5, HashSHA256, HashCRC64} {
+		var digest []byte
+		var v map[string]interface{}
+		r := mocks.NewResponseWithContent(jsonT)
+		err := Respond(r,
+			ByUnmarshallingJSON(&v),
+			ByHashingResponseBody(algorithm, &digest),
+			ByClosing())
+		if err != nil {
+			t.Errorf("autorest: ByHashingResponseBody(%s) failed (%v)", algorithm, err)
+		}
+		if len(digest) == 0 || v["name"] != "Rob Pike" {
+			t.Errorf("autorest: ByHashingResponseBody(%s) failed to hash the body while passing it along", algorithm)
+		}
+	}
+}
+
+func TestByHashingResponseBodyComputesMD5(t *testing.T) {
+	var digest []byte
+	r := mocks.NewResponseWithContent(jsonT)
+	Respond(r,
+		ByHashingResponseBody(HashMD5, &digest),
+		ByClosing())
+	expected := md5.Sum([]byte(jsonT))
+	if !bytes.Equal(digest, expected[:]) {
+		t.Errorf("autorest: ByHashingResponseBody computed %x -- expected %x", digest, expected)
+	}
+}
+
+func TestByHashingResponseBodyHashesUnreadContent(t *testing.T) {
+	var digest []byte
+	r := mocks.NewResponseWithContent(jsonT)
+	Respond(r,
+		ByHashingResponseBodyWithExpected(HashSHA256, nil, &digest),
+		ByClosing())
+	expected := sha256.Sum256([]byte(jsonT))
+	if !bytes.Equal(digest, expected[:]) {
+		t.Errorf("autorest: ByHashingResponseBody computed %x -- expected %x", digest, expected)
+	}
+}
+
+func TestByHashingResponseBodyVerifiesContentSHA256(t *testing.T) {
+	sum := sha256.Sum256([]byte(jsonT))
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, headerContentSHA256, base64.StdEncoding.EncodeToString(sum[:]))
+	if err := Respond(r, ByHashingResponseBody(HashSHA256, nil), ByClosing()); err != nil {
+		t.Errorf("autorest: ByHashingResponseBody rejected a matching x-ms-content-sha256 (%v)", err)
+	}
+
+	r = mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, headerContentSHA256, base64.StdEncoding.EncodeToString(make([]byte, sha256.Size)))
+	if err := Respond(r, ByHashingResponseBody(HashSHA256, nil), ByClosing()); err == nil {
+		t.Error("autorest: ByHashingResponseBody failed to return an error for a mismatched x-ms-content-sha256")
+	}
+}
+
+func TestByHashingResponseBodyVerifiesContentMD5(t *testing.T) {
+	sum := md5.Sum([]byte(jsonT))
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, "Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	if err := Respond(r, ByHashingResponseBody(HashMD5, nil), ByClosing()); err != nil {
+		t.Errorf("autorest: ByHashingResponseBody rejected a matching Content-MD5 (%v)", err)
+	}
+
+	r = mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, "Content-MD5", base64.StdEncoding.EncodeToString(make([]byte, md5.Size)))
+	if err := Respond(r, ByHashingResponseBody(HashMD5, nil), ByClosing()); err == nil {
+		t.Error("autorest: ByHashingResponseBody failed to return an error for a mismatched Content-MD5")
+	}
+}
+
+func TestByHashingResponseBodyVerifiesCRC64(t *testing.T) {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, crc64.Checksum([]byte(jsonT), crc64.MakeTable(CRC64Polynomial)))
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, "x-ms-content-crc64", base64.StdEncoding.EncodeToString(b))
+	if err := Respond(r, ByHashingResponseBody(HashCRC64, nil), ByClosing()); err != nil {
+		t.Errorf("autorest: ByHashingResponseBody rejected a matching x-ms-content-crc64 (%v)", err)
+	}
+}
+
+func TestByHashingResponseBodyWithExpectedReturnsErrorOnMismatch(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByHashingResponseBodyWithExpected(HashSHA256, []byte("not the digest"), nil),
+		ByClosing())
+	if err == nil {
+		t.Error("autorest: ByHashingResponseBodyWithExpected failed to return an error for a mismatched digest")
+	}
+}
+
+func TestByHashingResponseBodyReturnsErrorForUnknownAlgorithm(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
+	if err := Respond(r, ByHashingResponseBody("ROT13", nil), ByClosing()); err == nil {
+		t.Error("autorest: ByHashingResponseBody failed to return an error for an unsupported algorithm")
+	}
+}
+
+func TestByWritingResponseToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "response.json")
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		WithErrorUnlessOK(),
+		ByWritingResponseToFile(path, 0600),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByWritingResponseToFile failed (%v)", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil || string(b) != jsonT {
+		t.Errorf("autorest: ByWritingResponseToFile wrote %q (%v) -- expected %q", b, err, jsonT)
+	}
+}
+
+func TestByWritingResponseToFileTruncatesExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "response.json")
+	ioutil.WriteFile(path, []byte(strings.Repeat("x", 2*len(jsonT))), 0600)
+	Respond(mocks.NewResponseWithContent(jsonT),
+		ByWritingResponseToFile(path, 0600),
+		ByClosing())
+	if b, _ := ioutil.ReadFile(path); string(b) != jsonT {
+		t.Errorf("autorest: ByWritingResponseToFile failed to truncate the existing file -- found %q", b)
+	}
+}
+
+func TestByWritingResponseToFileSkipsFileOnResponderError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "response.json")
+	r := mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError)
+	Respond(r,
+		WithErrorUnlessOK(),
+		ByWritingResponseToFile(path, 0600),
+		ByClosing())
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("autorest: ByWritingResponseToFile created a file despite a Responder error")
+	}
+}
+
+func TestByWritingResponseToFileRemovesPartialFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "response.json")
+	r := mocks.NewResponseWithContent(jsonT)
+	r.Body.Close()
+	err := Respond(r,
+		ByWritingResponseToFile(path, 0600),
+		ByClosing())
+	if err == nil {
+		t.Error("autorest: ByWritingResponseToFile failed to return an error when reading the body failed")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("autorest: ByWritingResponseToFile failed to remove the partially written file")
+	}
+}
+
+func TestByAddingResponseHeader(t *testing.T) {
+	r := mocks.NewResponse()
+	err := Respond(r,
+		ByAddingResponseHeader("X-Trace-ID", "trace"),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByAddingResponseHeader failed (%v)", err)
+	}
+	if v := r.Header.Get("X-Trace-ID"); v != "trace" {
+		t.Errorf("autorest: ByAddingResponseHeader set the header to %q, expected %q", v, "trace")
+	}
+}
+
+func TestByAddingResponseHeaderExpandsPlaceholders(t *testing.T) {
+	r := mocks.NewResponse()
+	mocks.SetResponseHeader(r, headerRequestID, "request")
+	mocks.SetResponseHeader(r, headerCorrelationRequestID, "correlation")
+	err := Respond(r,
+		ByAddingResponseHeader("X-Trace-ID", "{correlationId}/{requestId}/{unknown}"),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByAddingResponseHeader failed (%v)", err)
+	}
+	if v := r.Header.Get("X-Trace-ID"); v != "correlation/request/{unknown}" {
+		t.Errorf("autorest: ByAddingResponseHeader set the header to %q, expected %q", v, "correlation/request/{unknown}")
+	}
+}
+
+func TestByAddingResponseHeaderRunsBeforeInnerResponder(t *testing.T) {
+	var seen string
+	r := mocks.NewResponse()
+	mocks.SetResponseHeader(r, headerRequestID, "request")
+	err := ByAddingResponseHeader("X-Trace-ID", "{requestId}")(ResponderFunc(func(resp *http.Response) error {
+		seen = resp.Header.Get("X-Trace-ID")
+		return nil
+	})).Respond(r)
+	if err != nil {
+		t.Fatalf("autorest: ByAddingResponseHeader failed (%v)", err)
+	}
+	if seen != "request" {
+		t.Errorf("autorest: ByAddingResponseHeader failed to set the header before the inner responder ran -- received %q", seen)
+	}
+}
+
+func TestBySettingRequestIDFromResponse(t *testing.T) {
+	var id string
+	r := mocks.NewResponse()
+	mocks.SetResponseHeader(r, "x-ms-request-id", "request-id")
+	Respond(r,
+		ByInspectingResponse(func(resp *http.Response, b []byte) {
+			id = RequestIDFromContext(resp.Request.Context())
+		}),
+		BySettingRequestIDFromResponse(),
+		ByClosing())
+	if id != "request-id" {
+		t.Errorf("autorest: BySettingRequestIDFromResponse failed to store the request ID -- received %q", id)
+	}
+	if r.Request.Context().Value(ContextRequestIDKey) != "request-id" {
+		t.Error("autorest: BySettingRequestIDFromResponse failed to store the request ID under ContextRequestIDKey")
+	}
+}
+
+func TestBySettingRequestIDFromResponseIgnoresMissingHeader(t *testing.T) {
+	r := mocks.NewResponse()
+	req := r.Request
+	Respond(r,
+		BySettingRequestIDFromResponse(),
+		ByClosing())
+	if r.Request != req {
+		t.Error("autorest: BySettingRequestIDFromResponse replaced the request despite a missing x-ms-request-id")
+	}
+}
+
+func TestCreateSafeResponderClosesBody(t *testing.T) {
+	r := mocks.NewResponseWithContent(jsonT)
+	var v map[string]interface{}
+	if err := CreateSafeResponder(ByUnmarshallingJSON(&v)).Respond(r); err != nil {
+		t.Fatalf("autorest: CreateSafeResponder failed (%v)", err)
+	}
+	if r.Body.(*mocks.Body).IsOpen() {
+		t.Error("autorest: CreateSafeResponder failed to close the response body")
+	}
+}
+
+func TestCreateSafeResponderDoesNotAddByClosingTwice(t *testing.T) {
+	for _, closer := range []RespondDecorator{ByClosing(), ByClosingWithError(), ByClosingOnce()} {
+		b := &countingCloser{Reader: strings.NewReader("")}
+		r := mocks.NewResponse()
+		r.Body = b
+		CreateSafeResponder(closer).Respond(r)
+		if b.closes != 1 {
+			t.Errorf("autorest: CreateSafeResponder closed the response body %d times -- expected 1", b.closes)
+		}
+	}
+}
+
+func TestCreateSafeResponderRespectsByClosingIfError(t *testing.T) {
+	r := mocks.NewResponse()
+	CreateSafeResponder(ByClosingIfError()).Respond(r)
+	if !r.Body.(*mocks.Body).IsOpen() {
+		t.Error("autorest: CreateSafeResponder added ByClosing despite the presence of ByClosingIfError")
+	}
+}
+
+func TestCreateSafeResponderDoesNotModifyDecorators(t *testing.T) {
+	decorators := make([]RespondDecorator, 1, 2)
+	decorators[0] = ByIgnoring()
+	CreateSafeResponder(decorators...)
+	if decorators[:2][1] != nil {
+		t.Error("autorest: CreateSafeResponder modified the passed decorators")
+	}
+}
+
+func TestByCapturingETag(t *testing.T) {
+	cases := map[string]string{
+		`"v1"`:   "v1",
+		"v1":     "v1",
+		`W/"v1"`: `W/"v1"`,
+	}
+	for header, expected := range cases {
+		var etag string
+		r := mocks.NewResponse()
+		mocks.SetResponseHeader(r, headerETag, header)
+		if err := Respond(r, ByCapturingETag(&etag), ByClosing()); err != nil {
+			t.Fatalf("autorest: ByCapturingETag failed (%v)", err)
+		}
+		if etag != expected {
+			t.Errorf("autorest: ByCapturingETag captured %s for %s, expected %s", etag, header, expected)
+		}
+	}
+}
+
+func TestByCapturingETagRoundTripsThroughWithIfMatch(t *testing.T) {
+	etag := "stale"
+	r := mocks.NewResponse()
+	mocks.SetResponseHeader(r, headerETag, `"0x8D1"`)
+	Respond(r, ByCapturingETag(&etag), ByClosing())
+
+	req, err := Prepare(mocks.NewRequest(), WithIfMatch(&etag))
+	if err != nil || req.Header.Get(headerIfMatch) != `"0x8D1"` {
+		t.Errorf("autorest: WithIfMatch failed to send the ETag captured by ByCapturingETag -- received %s (%v)", req.Header.Get(headerIfMatch), err)
+	}
+}
+
+func TestByParsingLinkHeader(t *testing.T) {
+	var next string
+	r := mocks.NewResponse()
+	r.Header = http.Header{}
+	r.Header.Add("Link", `<https://graph.microsoft.com/users?page=1>; rel="prev"`)
+	r.Header.Add("Link", `<https://graph.microsoft.com/users?page=3>; rel="last", <https://graph.microsoft.com/users?a=1,2>; title="a; b, c"; rel="next"`)
+	Respond(r,
+		ByParsingLinkHeader("next", &next),
+		ByClosing())
+	if next != "https://graph.microsoft.com/users?a=1,2" {
+		t.Errorf("autorest: ByParsingLinkHeader returned %q -- expected https://graph.microsoft.com/users?a=1,2", next)
+	}
+}
+
+func TestByParsingLinkHeaderMatchesAnyRelationType(t *testing.T) {
+	var last string
+	r := mocks.NewResponse()
+	mocks.SetResponseHeader(r, "Link", `<https://microsoft.com/a?page=9>; REL="next Last"`)
+	Respond(r,
+		ByParsingLinkHeader("last", &last),
+		ByClosing())
+	if last != "https://microsoft.com/a?page=9" {
+		t.Errorf("autorest: ByParsingLinkHeader returned %q -- expected https://microsoft.com/a?page=9", last)
+	}
+}
+
+func TestByParsingLinkHeaderSetsEmptyStringIfMissing(t *testing.T) {
+	next := "unchanged"
+	r := mocks.NewResponse()
+	mocks.SetResponseHeader(r, "Link", `<https://microsoft.com/a?page=1>; rel="prev"`)
+	Respond(r,
+		ByParsingLinkHeader("next", &next),
+		ByClosing())
+	if next != "" {
+		t.Errorf("autorest: ByParsingLinkHeader returned %q for a missing relation -- expected the empty string", next)
+	}
+}
+
+func TestByAuditingResponseWritesJSON(t *testing.T) {
+	var audit bytes.Buffer
+	var v map[string]interface{}
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, mocks.TestHeader, "value")
+	err := Respond(r,
+		ByUnmarshallingJSON(&v),
+		ByAuditingResponse(&audit, "json"),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByAuditingResponse failed (%v)", err)
+	}
+	if v["name"] != "Rob Pike" {
+		t.Error("autorest: ByAuditingResponse consumed the response body")
+	}
+	if strings.Count(audit.String(), "\n") != 1 {
+		t.Errorf("autorest: ByAuditingResponse wrote more than one line (%q)", audit.String())
+	}
+
+	entry := struct {
+		Timestamp time.Time
+		Method    string
+		URL       string
+		Status    int
+		Latency   string
+		Headers   http.Header
+		Body      string
+	}{}
+	if err := json.Unmarshal(audit.Bytes(), &entry); err != nil {
+		t.Fatalf("autorest: ByAuditingResponse wrote invalid JSON (%v)", err)
+	}
+	if entry.Timestamp.IsZero() || entry.Method != "GET" || entry.URL != mocks.TestURL || entry.Status != http.StatusOK ||
+		entry.Latency == "" || entry.Headers.Get(mocks.TestHeader) != "value" || entry.Body != jsonT {
+		t.Errorf("autorest: ByAuditingResponse wrote an incomplete entry -- %+v", entry)
+	}
+}
+
+func TestByAuditingResponseTruncatesBody(t *testing.T) {
+	var audit bytes.Buffer
+	r := mocks.NewResponseWithContent(strings.Repeat("x", 2*DefaultAuditBodyBytes))
+	Respond(r,
+		(func() RespondDecorator {
+			return func(r Responder) Responder {
+				return ResponderFunc(func(resp *http.Response) error {
+					ioutil.ReadAll(resp.Body)
+					return r.Respond(resp)
+				})
+			}
+		})(),
+		ByAuditingResponse(&audit, "json"),
+		ByClosing())
+	entry := struct{ Body string }{}
+	json.Unmarshal(audit.Bytes(), &entry)
+	if len(entry.Body) != DefaultAuditBodyBytes {
+		t.Errorf("autorest: ByAuditingResponse recorded %d body bytes -- expected %d", len(entry.Body), DefaultAuditBodyBytes)
+	}
+}
+
+func TestByAuditingResponseWritesText(t *testing.T) {
+	var audit bytes.Buffer
+	r := mocks.NewResponse()
+	Respond(r,
+		ByAuditingResponse(&audit, "text"),
+		ByClosing())
+	line := audit.String()
+	for _, field := range []string{"timestamp=", "method=GET", "url=\"" + mocks.TestURL + "\"", "status=200", "latency=", "headers=", "body="} {
+		if !strings.Contains(line, field) {
+			t.Errorf("autorest: ByAuditingResponse text entry lacks %s (%q)", field, line)
+		}
+	}
+}
+
+func TestByAuditingResponseReturnsErrorForUnknownFormat(t *testing.T) {
+	var audit bytes.Buffer
+	if err := Respond(mocks.NewResponse(), ByAuditingResponse(&audit, "xml"), ByClosing()); err == nil {
+		t.Error("autorest: ByAuditingResponse failed to return an error for an unsupported format")
+	}
+}
+
+func TestByStreamingBodyTo(t *testing.T) {
+	var body io.ReadCloser
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		WithErrorUnlessOK(),
+		ByStreamingBodyTo(&body),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByStreamingBodyTo failed (%v)", err)
+	}
+	if r.Body != http.NoBody {
+		t.Error("autorest: ByStreamingBodyTo failed to replace the response body with http.NoBody")
+	}
+	if !body.(*mocks.Body).IsOpen() {
+		t.Fatal("autorest: ByStreamingBodyTo allowed the chain to close the streamed body")
+	}
+	defer body.Close()
+	if b, _ := ioutil.ReadAll(body); string(b) != jsonT {
+		t.Errorf("autorest: ByStreamingBodyTo streamed %q -- expected %q", b, jsonT)
+	}
+}
+
+func TestByCapturingRedirectURL(t *testing.T) {
+	var u url.URL
+	r := mocks.NewResponseWithStatus("302 Found", http.StatusFound)
+	mocks.SetLocationHeader(r, "https://account.blob.core.windows.net/c/b?sig=secret")
+	err := Respond(r,
+		WithErrorUnlessOK(),
+		ByCapturingRedirectURL(&u),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByCapturingRedirectURL failed to suppress the status code error (%v)", err)
+	}
+	if u.String() != "https://account.blob.core.windows.net/c/b?sig=secret" {
+		t.Errorf("autorest: ByCapturingRedirectURL captured %s", u.String())
+	}
+}
+
+func TestByCapturingRedirectURLSuppressesStatusCodeRangeErrors(t *testing.T) {
+	var u url.URL
+	r := mocks.NewResponseWithStatus("302 Found", http.StatusFound)
+	mocks.SetLocationHeader(r, "https://account.blob.core.windows.net/c/b?sig=secret")
+	err := Respond(r,
+		WithErrorUnless2XX(),
+		ByCapturingRedirectURL(&u),
+		ByClosing())
+	if err != nil || u.String() != "https://account.blob.core.windows.net/c/b?sig=secret" {
+		t.Errorf("autorest: ByCapturingRedirectURL failed to suppress the status code error (%v, %s)", err, u.String())
+	}
+}
+
+func TestByCapturingRedirectURLReturnsOtherErrors(t *testing.T) {
+	var u url.URL
+	r := mocks.NewResponseWithStatus("302 Found", http.StatusFound)
+	mocks.SetLocationHeader(r, "https://microsoft.com/d")
+	e := fmt.Errorf("autorest: Faux Error")
+	err := Respond(r,
+		func(r Responder) Responder {
+			return ResponderFunc(func(resp *http.Response) error { return e })
+		},
+		ByCapturingRedirectURL(&u),
+		ByClosing())
+	if err != e || u.String() != "" {
+		t.Errorf("autorest: ByCapturingRedirectURL suppressed an error unrelated to the status code (%v, %s)", err, u.String())
+	}
+}
+
+func TestByCapturingRedirectURLResolvesRelativeLocations(t *testing.T) {
+	var u url.URL
+	r := mocks.NewResponseWithStatus("307 Temporary Redirect", http.StatusTemporaryRedirect)
+	mocks.SetLocationHeader(r, "/d?sig=secret")
+	Respond(r,
+		ByCapturingRedirectURL(&u),
+		ByClosing())
+	if u.String() != "https://microsoft.com/d?sig=secret" {
+		t.Errorf("autorest: ByCapturingRedirectURL captured %s -- expected https://microsoft.com/d?sig=secret", u.String())
+	}
+}
+
+func TestByCapturingRedirectURLIgnoresOtherStatusCodes(t *testing.T) {
+	var u url.URL
+	r := mocks.NewResponseWithStatus("302 Found", http.StatusFound)
+	mocks.SetLocationHeader(r, "https://microsoft.com/d")
+	err := Respond(r,
+		WithErrorUnlessOK(),
+		ByCapturingRedirectURL(&u, http.StatusSeeOther),
+		ByClosing())
+	if err == nil || u.String() != "" {
+		t.Errorf("autorest: ByCapturingRedirectURL handled a status code outside the passed set (%v, %s)", err, u.String())
+	}
+}
+
+func TestByCapturingRedirectURLReturnsErrorIfLocationIsMissing(t *testing.T) {
+	var u url.URL
+	r := mocks.NewResponseWithStatus("301 Moved Permanently", http.StatusMovedPermanently)
+	if err := Respond(r, ByCapturingRedirectURL(&u), ByClosing()); err == nil {
+		t.Error("autorest: ByCapturingRedirectURL failed to return an error for a missing Location header")
+	}
+}
+
+func TestByRecordingFinalURL(t *testing.T) {
+	var u *url.URL
+	r := mocks.NewResponse()
+	r.Request = mocks.NewRequestForURL("https://microsoft.com/redirected?sig=abc")
+	if err := Respond(r, ByRecordingFinalURL(&u), ByClosing()); err != nil {
+		t.Fatalf("autorest: ByRecordingFinalURL failed (%v)", err)
+	}
+	if u == nil || u.String() != "https://microsoft.com/redirected?sig=abc" {
+		t.Fatalf("autorest: ByRecordingFinalURL recorded %v", u)
+	}
+	if u == r.Request.URL {
+		t.Errorf("autorest: ByRecordingFinalURL recorded the request URL rather than a copy")
+	}
+}
+
+func TestByRecordingFinalURLRecordsOnError(t *testing.T) {
+	var u *url.URL
+	r := mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError)
+	r.Request = mocks.NewRequestForURL(mocks.TestURL)
+	err := Respond(r,
+		WithErrorUnlessOK(),
+		ByRecordingFinalURL(&u),
+		ByClosing())
+	if err == nil || u == nil || u.String() != mocks.TestURL {
+		t.Errorf("autorest: ByRecordingFinalURL failed to record the URL of a failed response (%v, %v)", err, u)
+	}
+}
+
+func TestByRecordingFinalURLClearsDestWithoutRequest(t *testing.T) {
+	u, _ := url.Parse(mocks.TestURL)
+	r := mocks.NewResponse()
+	r.Request = nil
+	Respond(r, ByRecordingFinalURL(&u), ByClosing())
+	if u != nil {
+		t.Errorf("autorest: ByRecordingFinalURL failed to clear dest for a response without a request")
+	}
+}
+
+func TestByUnmarshallingJSONWithPoolReusesDecoders(t *testing.T) {
+	var decoded []*mocks.T
+	var news int
+	pool := &sync.Pool{New: func() interface{} {
+		news++
+		return NewPooledJSONDecoder()
+	}}
+	responder := CreateResponder(
+		ByUnmarshallingJSONWithPool(pool, func() interface{} {
+			v := &mocks.T{}
+			decoded = append(decoded, v)
+			return v
+		}),
+		ByClosing())
+
+	for _, c := range []string{jsonT + "\n", `{"name":"Ken Thompson","age":80}`} {
+		if err := responder.Respond(mocks.NewResponseWithContent(c)); err != nil {
+			t.Fatalf("autorest: ByUnmarshallingJSONWithPool failed (%v)", err)
+		}
+	}
+	if len(decoded) != 2 || decoded[0].Name != "Rob Pike" || decoded[1].Name != "Ken Thompson" || decoded[1].Age != 80 {
+		t.Errorf("autorest: ByUnmarshallingJSONWithPool failed to decode each response into a new target")
+	}
+	if news == 0 || news > len(decoded) {
+		t.Errorf("autorest: ByUnmarshallingJSONWithPool created %d decoders for %d responses -- expected it to draw them from the pool", news, len(decoded))
+	}
+}
+
+func TestByUnmarshallingJSONWithPoolRecoversFromErrors(t *testing.T) {
+	v := &mocks.T{}
+	pool := NewJSONDecoderPool()
+	responder := CreateResponder(
+		ByUnmarshallingJSONWithPool(pool, func() interface{} { return v }),
+		ByClosing())
+
+	if err := responder.Respond(mocks.NewResponseWithContent("{not json")); err == nil {
+		t.Errorf("autorest: ByUnmarshallingJSONWithPool failed to return an error for an invalid body")
+	}
+	if err := responder.Respond(mocks.NewResponseWithContent(jsonT)); err != nil || v.Name != "Rob Pike" {
+		t.Errorf("autorest: ByUnmarshallingJSONWithPool reused a failed decoder (%v)", err)
+	}
+}
+
+func TestByUnmarshallingJSONWithPoolAcceptsJSONDecoders(t *testing.T) {
+	v := &mocks.T{}
+	pool := &sync.Pool{New: func() interface{} { return json.NewDecoder(strings.NewReader("")) }}
+	err := Respond(mocks.NewResponseWithContent(jsonT),
+		ByUnmarshallingJSONWithPool(pool, func() interface{} { return v }),
+		ByClosing())
+	if err != nil || v.Name != "Rob Pike" {
+		t.Errorf("autorest: ByUnmarshallingJSONWithPool failed to decode using a pool of *json.Decoder (%v)", err)
+	}
+	pool.New = nil
+	if d, ok := pool.Get().(*json.Decoder); ok && d != nil {
+		t.Errorf("autorest: ByUnmarshallingJSONWithPool failed to replace the *json.Decoder with a reusable decoder")
+	}
+}
+
+func TestByUnmarshallingJSONWithPoolIncludesBodyInError(t *testing.T) {
+	err := Respond(mocks.NewResponseWithContent("{not json"),
+		ByUnmarshallingJSONWithPool(NewJSONDecoderPool(), func() interface{} { return &mocks.T{} }),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), "{not json") {
+		t.Errorf("autorest: ByUnmarshallingJSONWithPool failed to include the body in the error -- received %v", err)
+	}
+}
+
+func TestPooledJSONDecoderDiscardsTrailingContent(t *testing.T) {
+	var a, b mocks.T
+	d := NewPooledJSONDecoder()
+	d.Reset(strings.NewReader(`{"name":"a"}{"name":"leftover"}`))
+	if err := d.Decode(&a); err != nil {
+		t.Fatalf("autorest: PooledJSONDecoder#Decode failed (%v)", err)
+	}
+	d.Reset(strings.NewReader(`{"name":"b"}`))
+	if err := d.Decode(&b); err != nil || b.Name != "b" {
+		t.Errorf("autorest: PooledJSONDecoder#Reset failed to discard content left from the previous reader -- received %q (%v)", b.Name, err)
+	}
+}
+
+const nestedJSONT = `{"response":{"properties":{"output":{"value":[{"name":"Rob Pike","age":42},{"name":"Ken Thompson","age":80}]}}}}`
+
+func TestByUnmarshallingJSONWithPath(t *testing.T) {
+	var v []mocks.T
+	r := mocks.NewResponseWithContent(nestedJSONT)
+	err := Respond(r,
+		ByUnmarshallingJSONWithPath("response.properties.output.value", &v),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingJSONWithPath failed (%v)", err)
+	}
+	if len(v) != 2 || v[1].Name != "Ken Thompson" {
+		t.Errorf("autorest: ByUnmarshallingJSONWithPath decoded %+v", v)
+	}
+}
+
+func TestByUnmarshallingJSONWithPathIndexesArrays(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(nestedJSONT)
+	err := Respond(r,
+		ByUnmarshallingJSONWithPath("response.properties.output.value.1", v),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingJSONWithPath failed (%v)", err)
+	}
+	if v.Name != "Ken Thompson" || v.Age != 80 {
+		t.Errorf("autorest: ByUnmarshallingJSONWithPath failed to index an array -- received %+v", v)
+	}
+}
+
+func TestByUnmarshallingJSONWithPathSelectsDocumentForEmptyPath(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSONWithPath("", v),
+		ByClosing())
+	if err != nil || v.Name != "Rob Pike" {
+		t.Errorf("autorest: ByUnmarshallingJSONWithPath failed to decode the whole document for an empty path (%v)", err)
+	}
+}
+
+func TestByUnmarshallingJSONWithPathReturnsErrorForMissingPath(t *testing.T) {
+	paths := []string{"response.missing", "response.properties.output.value.2", "response.properties.output.value.name"}
+	for _, p := range paths {
+		v := &mocks.T{}
+		r := mocks.NewResponseWithContent(nestedJSONT)
+		err := Respond(r,
+			ByUnmarshallingJSONWithPath(p, v),
+			ByClosing())
+		if err == nil {
+			t.Errorf("autorest: ByUnmarshallingJSONWithPath failed to return an error for the missing path %s", p)
+		}
+	}
+}
+
+func TestByUnmarshallingJSONWithContext(t *testing.T) {
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSONWithContext(context.Background(), v),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByUnmarshallingJSONWithContext failed (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("autorest: ByUnmarshallingJSONWithContext failed to properly unmarshal")
+	}
+}
+
+func TestByUnmarshallingJSONWithContextReturnsContextErrorIfCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	v := &mocks.T{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSONWithContext(ctx, v),
+		ByClosing())
+	if err != context.Canceled {
+		t.Errorf("autorest: ByUnmarshallingJSONWithContext returned %v -- expected context.Canceled", err)
+	}
+}
+
+func TestByUnmarshallingJSONWithContextAbortsStalledReads(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write([]byte(`{"name":`))
+
+	r := mocks.NewResponse()
+	r.Body = pr
+	err := Respond(r,
+		ByUnmarshallingJSONWithContext(ctx, &mocks.T{}),
+		ByClosing())
+	if err != context.DeadlineExceeded {
+		t.Errorf("autorest: ByUnmarshallingJSONWithContext returned %v -- expected context.DeadlineExceeded", err)
+	}
+}
+
+func newTLSResponse(raw []byte) *http.Response {
+	r := mocks.NewResponse()
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Raw: raw}}}
+	return r
+}
+
+func TestByEnforcingRequiredHeaders(t *testing.T) {
+	r := mocks.NewResponse()
+	mocks.SetResponseHeader(r, headerRequestID, "request-id")
+	mocks.SetResponseHeader(r, "Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+	err := Respond(r,
+		ByEnforcingRequiredHeaders(headerRequestID, "Date"),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByEnforcingRequiredHeaders returned an error when all headers were present (%v)", err)
+	}
+}
+
+func TestByEnforcingRequiredHeadersReturnsErrorForMissingHeaders(t *testing.T) {
+	mi := &mockInspector{}
+	r := mocks.NewResponse()
+	mocks.SetResponseHeader(r, headerRequestID, "")
+	mocks.SetResponseHeader(r, "Date", "Mon, 02 Jan 2006 15:04:05 GMT")
+	err := Respond(r,
+		mi.ByInspecting(),
+		ByEnforcingRequiredHeaders(headerRequestID, "Date", headerCorrelationRequestID),
+		ByClosingIfError())
+	if err == nil {
+		t.Fatalf("autorest: ByEnforcingRequiredHeaders failed to return an error for missing headers")
+	}
+	if !strings.Contains(err.Error(), headerRequestID) || !strings.Contains(err.Error(), headerCorrelationRequestID) || strings.Contains(err.Error(), "Date") {
+		t.Errorf("autorest: ByEnforcingRequiredHeaders failed to name exactly the missing headers -- received %v", err)
+	}
+	if mi.wasInvoked {
+		t.Errorf("autorest: ByEnforcingRequiredHeaders invoked the passed Responder despite missing headers")
+	}
+}
+
+func TestByAddingRequestIDGeneratesID(t *testing.T) {
+	var fromContext string
+	r := mocks.NewResponse()
+	r.Request = mocks.NewRequest()
+	err := Respond(r,
+		ByInspectingResponse(func(resp *http.Response, b []byte) {
+			fromContext = ClientRequestIDFromContext(resp.Request.Context())
+		}),
+		ByAddingRequestID(),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByAddingRequestID failed (%v)", err)
+	}
+	id := r.Header.Get(headerClientRequestID)
+	if len(id) != 36 {
+		t.Errorf("autorest: ByAddingRequestID failed to generate a UUID -- received %q", id)
+	}
+	if fromContext != id {
+		t.Errorf("autorest: ByAddingRequestID stored %q in the context, expected %q", fromContext, id)
+	}
+}
+
+func TestByAddingRequestIDUsesRequestID(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Request = mocks.NewRequest()
+	r.Request.Header.Set(headerClientRequestID, "request-id")
+	err := Respond(r,
+		ByAddingRequestID(),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByAddingRequestID failed (%v)", err)
+	}
+	if id := r.Header.Get(headerClientRequestID); id != "request-id" {
+		t.Errorf("autorest: ByAddingRequestID set %q, expected the ID sent with the request", id)
+	}
+	if id := ClientRequestIDFromContext(r.Request.Context()); id != "request-id" {
+		t.Errorf("autorest: ByAddingRequestID stored %q in the context, expected %q", id, "request-id")
+	}
+}
+
+func TestByAddingRequestIDKeepsResponseID(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Request = mocks.NewRequest()
+	r.Request.Header.Set(headerClientRequestID, "request-id")
+	mocks.SetResponseHeader(r, headerClientRequestID, "response-id")
+	err := Respond(r,
+		ByAddingRequestID(),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByAddingRequestID failed (%v)", err)
+	}
+	if id := r.Header.Get(headerClientRequestID); id != "response-id" {
+		t.Errorf("autorest: ByAddingRequestID replaced the ID returned by the service with %q", id)
+	}
+}
+
+func TestByVerifyingTLSCertificate(t *testing.T) {
+	fingerprint := sha256.Sum256([]byte("certificate"))
+	r := newTLSResponse([]byte("certificate"))
+	err := Respond(r,
+		ByVerifyingTLSCertificate(fingerprint[:]),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByVerifyingTLSCertificate rejected a matching certificate (%v)", err)
+	}
+}
+
+func TestByVerifyingTLSCertificateRejectsMismatchBeforeResponding(t *testing.T) {
+	fingerprint := sha256.Sum256([]byte("certificate"))
+	mi := &mockInspector{}
+	r := newTLSResponse([]byte("another certificate"))
+	err := Respond(r,
+		mi.ByInspecting(),
+		ByVerifyingTLSCertificate(fingerprint[:]),
+		ByClosing())
+	if err == nil {
+		t.Error("autorest: ByVerifyingTLSCertificate failed to return an error for a mismatched certificate")
+	}
+	if mi.wasInvoked {
+		t.Error("autorest: ByVerifyingTLSCertificate invoked the passed Responder despite a mismatched certificate")
+	}
+}
+
+func TestByVerifyingTLSCertificateRejectsResponsesWithoutTLS(t *testing.T) {
+	fingerprint := sha256.Sum256([]byte("certificate"))
+	if err := Respond(mocks.NewResponse(), ByVerifyingTLSCertificate(fingerprint[:]), ByClosing()); err == nil {
+		t.Error("autorest: ByVerifyingTLSCertificate failed to return an error for a response received without TLS")
+	}
+}
+
+func TestByCapturingRawResponse(t *testing.T) {
+	var raw []byte
+	var v map[string]interface{}
+	r := mocks.NewResponseWithContent(jsonT)
+	mocks.SetResponseHeader(r, mocks.TestHeader, "value")
+	err := Respond(r,
+		ByUnmarshallingJSON(&v),
+		ByCapturingRawResponse(&raw),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByCapturingRawResponse failed (%v)", err)
+	}
+	if v["name"] != "Rob Pike" {
+		t.Error("autorest: ByCapturingRawResponse failed to restore the response body")
+	}
+	s := string(raw)
+	if !strings.HasPrefix(s, "HTTP/1.0 200 OK\r\n") || !strings.Contains(s, "X-Test-Header: value\r\n") || !strings.HasSuffix(s, jsonT) {
+		t.Errorf("autorest: ByCapturingRawResponse captured an incomplete response -- %q", s)
+	}
+}
+
+func TestByTimeoutingResponse(t *testing.T) {
+	var v map[string]interface{}
+	r := mocks.NewResponseWithContent(jsonT)
+	err := Respond(r,
+		ByUnmarshallingJSON(&v),
+		ByTimeoutingResponse(time.Second),
+		ByClosing())
+	if err != nil || v["name"] != "Rob Pike" {
+		t.Errorf("autorest: ByTimeoutingResponse interfered with reading the body (%v)", err)
+	}
+}
+
+func TestByTimeoutingResponseFailsStalledReads(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write([]byte(`{"name":`))
+
+	r := mocks.NewResponse()
+	r.Body = pr
+	var b []byte
+	err := Respond(r,
+		(func() RespondDecorator {
+			return func(r Responder) Responder {
+				return ResponderFunc(func(resp *http.Response) error {
+					var err error
+					b, err = ioutil.ReadAll(resp.Body)
+					return err
+				})
+			}
+		})(),
+		ByTimeoutingResponse(10*time.Millisecond),
+		ByClosing())
+	if err != ErrResponseReadTimeout {
+		t.Errorf("autorest: ByTimeoutingResponse returned %v -- expected ErrResponseReadTimeout", err)
+	}
+	if string(b) != `{"name":` {
+		t.Errorf("autorest: ByTimeoutingResponse lost the content read before the stall -- received %q", b)
+	}
+	if _, err := r.Body.Read(make([]byte, 1)); err != ErrResponseReadTimeout {
+		t.Errorf("autorest: ByTimeoutingResponse permitted reading after a timeout (%v)", err)
+	}
+}
+
+func TestByUnmarshallingCSV(t *testing.T) {
+	var records [][]string
+	r := mocks.NewResponseWithContent("name,age\nRob Pike,42\n")
+	err := Respond(r,
+		ByUnmarshallingCSV(&records),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingCSV failed (%v)", err)
+	}
+	if !reflect.DeepEqual(records, [][]string{{"name", "age"}, {"Rob Pike", "42"}}) {
+		t.Errorf("autorest: ByUnmarshallingCSV returned %v", records)
+	}
+}
+
+func TestByUnmarshallingCSVAppliesOptions(t *testing.T) {
+	var records [][]string
+	r := mocks.NewResponseWithContent("name;age\n\"Rob \"Pike\";42\n")
+	err := Respond(r,
+		ByUnmarshallingCSV(&records, func(cr *csv.Reader) {
+			cr.Comma = ';'
+			cr.LazyQuotes = true
+		}),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingCSV failed (%v)", err)
+	}
+	if len(records) != 2 || records[1][1] != "42" {
+		t.Errorf("autorest: ByUnmarshallingCSV ignored the options -- returned %v", records)
+	}
+}
+
+func TestByUnmarshallingCSVReportsLine(t *testing.T) {
+	var records [][]string
+	r := mocks.NewResponseWithContent("name,age\nRob Pike,42\nKen Thompson\n")
+	err := Respond(r,
+		ByUnmarshallingCSV(&records),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("autorest: ByUnmarshallingCSV failed to report the failing line (%v)", err)
+	}
+	if records != nil {
+		t.Errorf("autorest: ByUnmarshallingCSV set the records despite an error -- %v", records)
+	}
+}
+
+func TestByNormalizingHeaders(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Header = http.Header{
+		"content-type":    {"application/json"},
+		"Content-Type":    {"text/plain", "application/json"},
+		"x-ms-request-id": {"request-id"},
+	}
+	Respond(r,
+		ByNormalizingHeaders(),
+		ByClosing())
+	expected := http.Header{
+		"Content-Type":    {"text/plain", "application/json"},
+		"X-Ms-Request-Id": {"request-id"},
+	}
+	if !reflect.DeepEqual(r.Header, expected) {
+		t.Errorf("autorest: ByNormalizingHeaders returned %v -- expected %v", r.Header, expected)
+	}
+}
+
+func TestByNormalizingHeadersMergesNonCanonicalKeys(t *testing.T) {
+	r := mocks.NewResponse()
+	r.Header = http.Header{
+		"x-test-header": {"a"},
+		"X-TEST-HEADER": {"b", "a"},
+	}
+	Respond(r,
+		ByNormalizingHeaders(),
+		ByClosing())
+	if v := r.Header["X-Test-Header"]; len(v) != 2 || !containsString(v, "a") || !containsString(v, "b") {
+		t.Errorf("autorest: ByNormalizingHeaders merged the values into %v -- expected a and b", v)
+	}
+}
+
+func TestByRetryAfterRespectingUsesDefaultClock(t *testing.T) {
+	tc := useTestClock(t)
+	s := mocks.NewSender()
+
+	r := mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	mocks.SetResponseHeader(r, "Retry-After", "120")
+	Respond(r,
+		ByRetryAfterRespecting(s, 3),
+		ByClosing())
+	if !reflect.DeepEqual(tc.Sleeps(), []time.Duration{120 * time.Second}) {
+		t.Errorf("autorest: ByRetryAfterRespecting slept %v -- expected [2m0s]", tc.Sleeps())
+	}
+}
+
+func TestByRetryingOnStatusCodeUsesDefaultClock(t *testing.T) {
+	tc := useTestClock(t)
+	s := mocks.NewSender()
+	s.EmitStatus("500 InternalServerError", http.StatusInternalServerError)
+
+	r := newResendableResponse("500 InternalServerError", http.StatusInternalServerError)
+	Respond(r,
+		ByRetryingOnStatusCode(s, 3, http.StatusInternalServerError),
+		ByClosing())
+	if !reflect.DeepEqual(tc.Sleeps(), []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}) {
+		t.Errorf("autorest: ByRetryingOnStatusCode slept %v -- expected [1s 2s 4s]", tc.Sleeps())
+	}
+}
+
+func TestByRetryingOnStatusCodeCapsBackoff(t *testing.T) {
+	tc := useTestClock(t)
+	s := mocks.NewSender()
+	s.EmitStatus("500 InternalServerError", http.StatusInternalServerError)
+
+	r := newResendableResponse("500 InternalServerError", http.StatusInternalServerError)
+	Respond(r,
+		ByRetryingOnStatusCode(s, 70, http.StatusInternalServerError),
+		ByClosing())
+	sleeps := tc.Sleeps()
+	if len(sleeps) != 70 {
+		t.Fatalf("autorest: ByRetryingOnStatusCode slept %d times -- expected 70", len(sleeps))
+	}
+	for i, d := range sleeps {
+		if d <= 0 || d > MaxRetryBackoff || (i > 0 && d < sleeps[i-1]) {
+			t.Fatalf("autorest: ByRetryingOnStatusCode slept %v before attempt %d -- expected a growing delay of at most %v", d, i+1, MaxRetryBackoff)
+		}
+	}
+	if sleeps[len(sleeps)-1] != MaxRetryBackoff {
+		t.Errorf("autorest: ByRetryingOnStatusCode failed to cap the backoff at %v -- received %v", MaxRetryBackoff, sleeps[len(sleeps)-1])
+	}
+}
+
+func newEventuallyConsistentSender(notFound int) (Sender, *int) {
+	attempts := 0
+	return SenderFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		resp := mocks.NewResponseWithStatus("404 NotFound", http.StatusNotFound)
+		if attempts > notFound {
+			resp = mocks.NewResponse()
+		}
+		resp.Request = r
+		return resp, nil
+	}), &attempts
+}
+
+func isNotFound(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusNotFound
+}
+
+func TestByWaitingForEventualConsistency(t *testing.T) {
+	tc := useTestClock(t)
+	s, attempts := newEventuallyConsistentSender(2)
+
+	r := newResendableResponse("404 NotFound", http.StatusNotFound)
+	err := Respond(r,
+		ByWaitingForEventualConsistency(s, time.Minute, isNotFound),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByWaitingForEventualConsistency failed (%v)", err)
+	}
+	if r.StatusCode != http.StatusOK || *attempts != 3 {
+		t.Errorf("autorest: ByWaitingForEventualConsistency returned %v after %d attempts -- expected 200 after 3", r.StatusCode, *attempts)
+	}
+	if !reflect.DeepEqual(tc.Sleeps(), []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}) {
+		t.Errorf("autorest: ByWaitingForEventualConsistency slept %v -- expected [1s 2s 4s]", tc.Sleeps())
+	}
+}
+
+func TestByWaitingForEventualConsistencyStopsAtMaxWait(t *testing.T) {
+	tc := useTestClock(t)
+	s, _ := newEventuallyConsistentSender(100)
+
+	r := newResendableResponse("404 NotFound", http.StatusNotFound)
+	err := Respond(r,
+		ByWaitingForEventualConsistency(s, 5*time.Second, isNotFound),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByWaitingForEventualConsistency failed (%v)", err)
+	}
+	if r.StatusCode != http.StatusNotFound {
+		t.Errorf("autorest: ByWaitingForEventualConsistency failed to pass along the last response -- received %v", r.StatusCode)
+	}
+	if !reflect.DeepEqual(tc.Sleeps(), []time.Duration{time.Second, 2 * time.Second, 2 * time.Second}) {
+		t.Errorf("autorest: ByWaitingForEventualConsistency slept %v -- expected [1s 2s 2s]", tc.Sleeps())
+	}
+}
+
+func TestByWaitingForEventualConsistencyStopsWhenCancelled(t *testing.T) {
+	useTestClock(t)
+	s, attempts := newEventuallyConsistentSender(100)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := mocks.NewResponseWithStatus("404 NotFound", http.StatusNotFound)
+	r.Request = r.Request.WithContext(ctx)
+	err := Respond(r,
+		ByWaitingForEventualConsistency(s, time.Minute, isNotFound),
+		ByClosing())
+	if err != context.Canceled || *attempts != 0 {
+		t.Errorf("autorest: ByWaitingForEventualConsistency failed to stop when cancelled -- received %v after %d attempts", err, *attempts)
+	}
+}
+
+func respondThroughBreaker(cb RespondDecorator, code int, invoked *int) error {
+	return Respond(mocks.NewResponseWithStatus(http.StatusText(code), code),
+		(func() RespondDecorator {
+			return func(r Responder) Responder {
+				return ResponderFunc(func(resp *http.Response) error {
+					*invoked++
+					return r.Respond(resp)
+				})
+			}
+		})(),
+		cb)
+}
+
+func TestByCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	useTestClock(t)
+	cb := ByCircuitBreaker(2, 1, time.Minute)
+	var invoked int
+
+	respondThroughBreaker(cb, http.StatusServiceUnavailable, &invoked)
+	respondThroughBreaker(cb, http.StatusOK, &invoked)
+	respondThroughBreaker(cb, http.StatusServiceUnavailable, &invoked)
+	if err := respondThroughBreaker(cb, http.StatusOK, &invoked); err != nil {
+		t.Fatalf("autorest: ByCircuitBreaker opened on non-consecutive failures (%v)", err)
+	}
+	respondThroughBreaker(cb, http.StatusServiceUnavailable, &invoked)
+	respondThroughBreaker(cb, http.StatusServiceUnavailable, &invoked)
+
+	err := respondThroughBreaker(cb, http.StatusOK, &invoked)
+	if !errors.Is(err, ErrCircuitOpen) || invoked != 6 {
+		t.Errorf("autorest: ByCircuitBreaker failed to open after consecutive failures -- received %v after %d invocations", err, invoked)
+	}
+}
+
+func TestByCircuitBreakerClosesAfterSuccessesWhenHalfOpen(t *testing.T) {
+	tc := useTestClock(t)
+	cb := ByCircuitBreaker(1, 2, time.Minute)
+	var invoked int
+
+	respondThroughBreaker(cb, http.StatusInternalServerError, &invoked)
+	tc.Advance(time.Minute)
+	if err := respondThroughBreaker(cb, http.StatusOK, &invoked); err != nil {
+		t.Fatalf("autorest: ByCircuitBreaker failed to turn half-open after the open duration (%v)", err)
+	}
+	respondThroughBreaker(cb, http.StatusOK, &invoked)
+	respondThroughBreaker(cb, http.StatusInternalServerError, &invoked)
+	if err := respondThroughBreaker(cb, http.StatusOK, &invoked); !errors.Is(err, ErrCircuitOpen) || invoked != 4 {
+		t.Errorf("autorest: ByCircuitBreaker failed to close after consecutive successes -- received %v after %d invocations", err, invoked)
+	}
+}
+
+func TestByCircuitBreakerReopensOnFailureWhenHalfOpen(t *testing.T) {
+	tc := useTestClock(t)
+	cb := ByCircuitBreaker(1, 2, time.Minute)
+	var invoked int
+
+	respondThroughBreaker(cb, http.StatusInternalServerError, &invoked)
+	tc.Advance(time.Minute)
+	respondThroughBreaker(cb, http.StatusTooManyRequests, &invoked)
+	if err := respondThroughBreaker(cb, http.StatusOK, &invoked); !errors.Is(err, ErrCircuitOpen) || invoked != 2 {
+		t.Errorf("autorest: ByCircuitBreaker failed to re-open on a failure while half-open -- received %v after %d invocations", err, invoked)
+	}
+}
+
+func TestByCircuitBreakerCountsResponderErrorsAsFailures(t *testing.T) {
+	useTestClock(t)
+	cb := ByCircuitBreaker(1, 1, time.Minute)
+	Respond(mocks.NewResponse(), WithErrorUnlessStatusCode(http.StatusCreated), cb)
+
+	r := mocks.NewResponse()
+	err := Respond(r, cb)
+	if !errors.Is(err, ErrCircuitOpen) || r.Body.(*mocks.Body).IsOpen() {
+		t.Errorf("autorest: ByCircuitBreaker failed to open, closing the body, after a Responder error -- received %v", err)
+	}
+}
+
+func TestByCircuitBreakerHandlesNilResponse(t *testing.T) {
+	useTestClock(t)
+	cb := CreateResponder(ByCircuitBreaker(1, 1, time.Minute))
+	if err := cb.Respond(nil); err != nil {
+		t.Fatalf("autorest: ByCircuitBreaker failed for a nil response (%v)", err)
+	}
+	cb = CreateResponder(WithErrorUnlessOK(), ByCircuitBreaker(1, 1, time.Minute))
+	cb.Respond(mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError))
+	if err := cb.Respond(nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("autorest: ByCircuitBreaker failed to reject a nil response while open -- received %v", err)
+	}
+}
+
+func TestByCircuitBreakerClearsProbeOnPanic(t *testing.T) {
+	tc := useTestClock(t)
+	cb := ByCircuitBreaker(1, 1, time.Minute)
+	panicking := CreateResponder(func(r Responder) Responder {
+		return ResponderFunc(func(resp *http.Response) error {
+			panic("autorest: faux panic")
+		})
+	}, cb)
+
+	Respond(mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError), cb)
+	tc.Advance(time.Minute)
+	func() {
+		defer func() { recover() }()
+		panicking.Respond(mocks.NewResponse())
+	}()
+	if err := Respond(mocks.NewResponse(), cb); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("autorest: ByCircuitBreaker failed to re-open after a panicking probe -- received %v", err)
+	}
+	tc.Advance(time.Minute)
+	if err := Respond(mocks.NewResponse(), cb); err != nil {
+		t.Errorf("autorest: ByCircuitBreaker remained open after a panicking probe (%v)", err)
+	}
+}
+
+func TestByCircuitBreakerIsSafeForConcurrentUse(t *testing.T) {
+	useTestClock(t)
+	cb := CreateResponder(ByCircuitBreaker(5, 1, time.Minute))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cb.Respond(mocks.NewResponseWithStatus("503 ServiceUnavailable", http.StatusServiceUnavailable))
+		}()
+	}
+	wg.Wait()
+	if err := cb.Respond(mocks.NewResponse()); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("autorest: ByCircuitBreaker failed to open under concurrent failures -- received %v", err)
+	}
+}
+
+func TestByMeasuringLatencyUsesDefaultClock(t *testing.T) {
+	tc := useTestClock(t)
+	var d time.Duration
+	Respond(mocks.NewResponse(),
+		(func() RespondDecorator {
+			return func(r Responder) Responder {
+				return ResponderFunc(func(resp *http.Response) error {
+					tc.Advance(3 * time.Second)
+					return r.Respond(resp)
+				})
+			}
+		})(),
+		ByMeasuringLatency(&d),
+		ByClosing())
+	if d != 3*time.Second {
+		t.Errorf("autorest: ByMeasuringLatency measured %v -- expected 3s", d)
+	}
+}
+
+func newBatchExtractor(statuses ...int) func(*http.Response) ([]*http.Response, error) {
+	return func(resp *http.Response) ([]*http.Response, error) {
+		subs := make([]*http.Response, len(statuses))
+		for i, code := range statuses {
+			subs[i] = mocks.NewResponseWithStatus(fmt.Sprintf("%d %s", code, http.StatusText(code)), code)
+		}
+		if len(statuses) > 1 {
+			subs[1].Body = mocks.NewBody(`{"error":{"code":"BlobNotFound","message":"The specified blob does not exist."}}`)
+		}
+		return subs, nil
+	}
+}
+
+func TestByGroupingErrors(t *testing.T) {
+	r := mocks.NewResponseWithStatus("202 Accepted", http.StatusAccepted)
+	err := Respond(r,
+		ByGroupingErrors(newBatchExtractor(http.StatusAccepted, http.StatusNotFound, http.StatusForbidden)),
+		ByClosing())
+	if err == nil {
+		t.Fatal("autorest: ByGroupingErrors failed to return an error for failed sub-responses")
+	}
+	if !strings.Contains(err.Error(), "2 of 3 sub-responses failed") {
+		t.Errorf("autorest: ByGroupingErrors returned an unexpected error -- %v", err)
+	}
+
+	var de *DetailedError
+	if !errors.As(err.(Error).Original(), &de) || de.ServiceError == nil || de.ServiceError.Code != "BlobNotFound" {
+		t.Errorf("autorest: ByGroupingErrors failed to report the sub-response service errors -- %v", err)
+	}
+}
+
+func TestByGroupingErrorsSucceedsIfAllSubResponsesSucceed(t *testing.T) {
+	r := mocks.NewResponseWithStatus("202 Accepted", http.StatusAccepted)
+	err := Respond(r,
+		ByGroupingErrors(newBatchExtractor(http.StatusAccepted, http.StatusNoContent)),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByGroupingErrors returned an unexpected error (%v)", err)
+	}
+}
+
+func TestByGroupingErrorsReturnsExtractorError(t *testing.T) {
+	r := mocks.NewResponse()
+	err := Respond(r,
+		ByGroupingErrors(func(*http.Response) ([]*http.Response, error) {
+			return nil, fmt.Errorf("autorest: Faux Extractor Error")
+		}),
+		ByClosing())
+	if err == nil {
+		t.Error("autorest: ByGroupingErrors failed to return the extractor error")
+	}
+}
+
+func TestByObservingStatusCode(t *testing.T) {
+	var observed []int
+	observer := func(statusCode int) { observed = append(observed, statusCode) }
+
+	Respond(mocks.NewResponse(),
+		ByObservingStatusCode(observer),
+		ByClosing())
+	Respond(mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError),
+		WithErrorUnlessOK(),
+		ByObservingStatusCode(observer),
+		ByClosing())
+	if !reflect.DeepEqual(observed, []int{http.StatusOK, http.StatusInternalServerError}) {
+		t.Errorf("autorest: ByObservingStatusCode observed %v -- expected [200 500]", observed)
+	}
+}
+
+func TestBySettingRequestIDFromResponseSetsCorrelationID(t *testing.T) {
+	var id string
+	r := mocks.NewResponse()
+	mocks.SetResponseHeader(r, "x-ms-correlation-request-id", "correlation-id")
+	Respond(r,
+		ByInspectingResponse(func(resp *http.Response, b []byte) {
+			id = CorrelationIDFromContext(resp.Request.Context())
+		}),
+		BySettingRequestIDFromResponse(),
+		ByClosing())
+	if id != "correlation-id" {
+		t.Errorf("autorest: BySettingRequestIDFromResponse failed to store the correlation ID -- received %q", id)
+	}
+}
+
+func TestByUnmarshallingJSONStream(t *testing.T) {
+	var people []mocks.T
+	r := mocks.NewResponseWithContent("{\"name\":\"Rob Pike\",\"age\":42}\n\n  \r\n{\"name\":\"Ken Thompson\",\"age\":80}")
+	err := Respond(r,
+		ByUnmarshallingJSONStream(func(m json.RawMessage) error {
+			var p mocks.T
+			if err := json.Unmarshal(m, &p); err != nil {
+				return err
+			}
+			people = append(people, p)
+			return nil
+		}),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingJSONStream failed (%v)", err)
+	}
+	if !reflect.DeepEqual(people, []mocks.T{{Name: "Rob Pike", Age: 42}, {Name: "Ken Thompson", Age: 80}}) {
+		t.Errorf("autorest: ByUnmarshallingJSONStream decoded %+v", people)
+	}
+}
+
+func TestByUnmarshallingJSONStreamStopsOnCallbackError(t *testing.T) {
+	calls := 0
+	stop := fmt.Errorf("stop")
+	r := mocks.NewResponseWithContent("1\n2\n3\n")
+	err := Respond(r,
+		ByUnmarshallingJSONStream(func(m json.RawMessage) error {
+			calls++
+			if string(m) == "2" {
+				return stop
+			}
+			return nil
+		}),
+		ByClosing())
+	if err != stop {
+		t.Errorf("autorest: ByUnmarshallingJSONStream returned %v, expected the error of the callback", err)
+	}
+	if calls != 2 {
+		t.Errorf("autorest: ByUnmarshallingJSONStream invoked the callback %d times, expected 2", calls)
+	}
+}
+
+func TestByUnmarshallingJSONStreamReturnsErrorForInvalidLine(t *testing.T) {
+	r := mocks.NewResponseWithContent("{}\nnot json\n{}\n")
+	err := Respond(r,
+		ByUnmarshallingJSONStream(func(m json.RawMessage) error { return nil }),
+		ByClosing())
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("autorest: ByUnmarshallingJSONStream failed to identify the invalid line -- received %v", err)
+	}
+}
+
+func TestByAppendingToSlice(t *testing.T) {
+	people := []mocks.T{{Name: "Ken Thompson", Age: 73}}
+	for _, page := range []string{`[{"name":"Rob Pike","age":42}]`, `[{"name":"Robert Griesemer","age":51},{"name":"Russ Cox","age":48}]`} {
+		err := Respond(mocks.NewResponseWithContent(page),
+			ByAppendingToSlice(&people),
+			ByClosing())
+		if err != nil {
+			t.Fatalf("autorest: ByAppendingToSlice failed (%v)", err)
+		}
+	}
+	if len(people) != 4 || people[0].Name != "Ken Thompson" || people[1].Name != "Rob Pike" || people[3].Name != "Russ Cox" {
+		t.Errorf("autorest: ByAppendingToSlice accumulated %v", people)
+	}
+}
+
+func TestByAppendingToSliceRejectsNonSlicePointers(t *testing.T) {
+	var s []int
+	var m map[string]interface{}
+	for _, v := range []interface{}{s, &m, (*[]int)(nil)} {
+		if err := Respond(mocks.NewResponseWithContent("[1]"), ByAppendingToSlice(v), ByClosing()); err == nil {
+			t.Errorf("autorest: ByAppendingToSlice failed to return an error for %T", v)
+		}
+	}
+}
+
+func TestByAppendingToSliceReturnsErrorForInvalidJSON(t *testing.T) {
+	var s []int
+	err := Respond(mocks.NewResponseWithContent(`{"value":[1]}`),
+		ByAppendingToSlice(&s),
+		ByClosing())
+	if err == nil {
+		t.Error("autorest: ByAppendingToSlice failed to return an error for a body that is not a JSON array")
+	}
+}
+
+func TestByParallelUnmarshalling(t *testing.T) {
+	var person mocks.T
+	var tags []string
+	var missing map[string]string
+	r := mocks.NewResponseWithContent(`{"person":{"name":"Rob Pike","age":42},"tags":["go","plan9"],"ignored":true}`)
+	err := Respond(r,
+		ByParallelUnmarshalling(map[string]interface{}{
+			"person":  &person,
+			"tags":    &tags,
+			"missing": &missing,
+		}),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByParallelUnmarshalling failed (%v)", err)
+	}
+	if person.Name != "Rob Pike" || person.Age != 42 {
+		t.Errorf("autorest: ByParallelUnmarshalling failed to decode an object member -- received %+v", person)
+	}
+	if !reflect.DeepEqual(tags, []string{"go", "plan9"}) {
+		t.Errorf("autorest: ByParallelUnmarshalling failed to decode an array member -- received %v", tags)
+	}
+	if missing != nil {
+		t.Errorf("autorest: ByParallelUnmarshalling modified the target of a missing member")
+	}
+}
+
+func TestByParallelUnmarshallingJoinsErrors(t *testing.T) {
+	var a, b int
+	var c string
+	r := mocks.NewResponseWithContent(`{"a":"one","b":"two","c":"three"}`)
+	err := Respond(r,
+		ByParallelUnmarshalling(map[string]interface{}{"a": &a, "b": &b, "c": &c}),
+		ByClosing())
+	if err == nil {
+		t.Fatalf("autorest: ByParallelUnmarshalling failed to return an error for undecodable members")
+	}
+	if !strings.Contains(err.Error(), "member a") || !strings.Contains(err.Error(), "member b") {
+		t.Errorf("autorest: ByParallelUnmarshalling failed to report every failing member -- received %v", err)
+	}
+	if c != "three" {
+		t.Errorf("autorest: ByParallelUnmarshalling failed to decode the members that succeeded")
+	}
+}
+
+func TestByParallelUnmarshallingReturnsErrorForNonObject(t *testing.T) {
+	var v int
+	r := mocks.NewResponseWithContent(`[1,2,3]`)
+	err := Respond(r,
+		ByParallelUnmarshalling(map[string]interface{}{"v": &v}),
+		ByClosing())
+	if err == nil {
+		t.Errorf("autorest: ByParallelUnmarshalling failed to return an error for a body that is not a JSON object")
+	}
+}
+
+func TestByParallelUnmarshallingAcceptsEmptyBody(t *testing.T) {
+	var v int
+	r := mocks.NewResponseWithStatus("204 NoContent", http.StatusNoContent)
+	err := Respond(r,
+		ByParallelUnmarshalling(map[string]interface{}{"v": &v}),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: ByParallelUnmarshalling returned an error for an empty body (%v)", err)
+	}
+}
+
+func TestByTranscodingBody(t *testing.T) {
+	v := struct {
+		Person struct {
+			Name struct {
+				Value string `json:"$"`
+			}
+		}
+	}{}
+	r := mocks.NewResponseWithContent(xmlT)
+	mocks.SetResponseHeader(r, headerContentType, "application/xml")
+	err := Respond(r,
+		ByUnmarshallingJSON(&v),
+		ByTranscodingBody("xml", "json"),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByTranscodingBody failed (%v)", err)
+	}
+	if v.Person.Name.Value != "Rob Pike" {
+		t.Errorf("autorest: ByTranscodingBody failed to convert the body -- decoded %+v", v)
+	}
+	if ct := r.Header.Get(headerContentType); ct != mimeTypeJSON {
+		t.Errorf("autorest: ByTranscodingBody set Content-Type to %q -- expected %q", ct, mimeTypeJSON)
+	}
+}
+
+func TestByTranscodingBodyReturnsErrorForUnsupportedFormats(t *testing.T) {
+	if err := Respond(mocks.NewResponseWithContent(jsonT), ByTranscodingBody("json", "xml"), ByClosing()); err == nil {
+		t.Error("autorest: ByTranscodingBody failed to return an error for an unsupported conversion")
+	}
+}
+
+func TestByTranscodingBodyReturnsErrorForInvalidXML(t *testing.T) {
+	if err := Respond(mocks.NewResponseWithContent("<Person>"), ByTranscodingBody("xml", "json"), ByClosing()); err == nil {
+		t.Error("autorest: ByTranscodingBody failed to return an error for invalid XML")
+	}
+}
+
+func TestByRecordingRetryCount(t *testing.T) {
+	attempts := 0
+	r := CreateResponder(
+		WithErrorUnlessOK(),
+		ByRecordingRetryCount(&attempts),
+		ByClosing())
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK} {
+		if err := r.Respond(mocks.NewResponseWithStatus(http.StatusText(status), status)); err == nil {
+			break
+		}
+	}
+	if attempts != 3 {
+		t.Errorf("autorest: ByRecordingRetryCount counted %d attempts -- expected 3", attempts)
+	}
+}
+
+func TestByRecordingRetryCountCountsBuiltInRetries(t *testing.T) {
+	attempts := 0
+	s := mocks.NewSender()
+	s.EmitStatus("503 ServiceUnavailable", http.StatusServiceUnavailable)
+
+	r := newResendableResponse("503 ServiceUnavailable", http.StatusServiceUnavailable)
+	Respond(r,
+		ByRetryingOnStatusCodeWithBackoff(s, 2, 0, http.StatusServiceUnavailable),
+		ByRecordingRetryCount(&attempts),
+		ByClosing())
+	if attempts != 3 || s.Attempts() != 2 {
+		t.Errorf("autorest: ByRecordingRetryCount counted %d attempts -- expected 3", attempts)
+	}
+}
+
+func TestNoopResponder(t *testing.T) {
+	r := mocks.NewResponseWithStatus("500 InternalServerError", http.StatusInternalServerError)
+	if err := NoopResponder.Respond(r); err != nil {
+		t.Errorf("autorest: NoopResponder returned an unexpected error (%v)", err)
+	}
+	if !r.Body.(*mocks.Body).IsOpen() || r.StatusCode != http.StatusInternalServerError {
+		t.Error("autorest: NoopResponder modified the response")
+	}
+}
+
+func TestNoopResponderIsComparable(t *testing.T) {
+	if CreateResponder() != NoopResponder {
+		t.Error("autorest: CreateResponder without decorators failed to return NoopResponder")
+	}
+	if Responder(ResponderFunc(func(*http.Response) error { return nil })) == NoopResponder {
+		t.Error("autorest: NoopResponder compared equal to another Responder")
+	}
+}
+
+type testCat struct {
+	Kind  string `json:"kind"`
+	Lives int    `json:"lives"`
+}
+
+type testDog struct {
+	Kind  string `json:"kind"`
+	Breed string `json:"breed"`
+}
+
+var testPetRegistry = map[string]func() interface{}{
+	"cat": func() interface{} { return &testCat{} },
+	"dog": func() interface{} { return &testDog{} },
+}
+
+func TestByUnmarshallingJSONWithFallback(t *testing.T) {
+	var v interface{}
+	err := Respond(mocks.NewResponseWithContent(`{"kind":"dog","breed":"corgi"}`),
+		ByUnmarshallingJSONWithFallback("kind", testPetRegistry, &v),
+		ByClosing())
+	if err != nil {
+		t.Fatalf("autorest: ByUnmarshallingJSONWithFallback failed (%v)", err)
+	}
+	if d, ok := v.(*testDog); !ok || d.Breed != "corgi" {
+		t.Errorf("autorest: ByUnmarshallingJSONWithFallback decoded %#v -- expected a *testDog", v)
+	}
+}
+
+func TestByUnmarshallingJSONWithFallbackUsesFallback(t *testing.T) {
+	registry := map[string]func() interface{}{
+		"cat": testPetRegistry["cat"],
+		"":    func() interface{} { return &map[string]interface{}{} },
+	}
+	var v interface{}
+	Respond(mocks.NewResponseWithContent(`{"kind":"fish","fins":4}`),
+		ByUnmarshallingJSONWithFallback("kind", registry, &v),
+		ByClosing())
+	if m, ok := v.(*map[string]interface{}); !ok || (*m)["fins"] != 4.0 {
+		t.Errorf("autorest: ByUnmarshallingJSONWithFallback decoded %#v -- expected the fallback type", v)
+	}
+}
+
+func TestByUnmarshallingJSONWithFallbackReturnsErrorForUnregisteredType(t *testing.T) {
+	var v interface{}
+	err := Respond(mocks.NewResponseWithContent(`{"kind":"fish"}`),
+		ByUnmarshallingJSONWithFallback("kind", testPetRegistry, &v),
+		ByClosing())
+	if err == nil || v != nil {
+		t.Errorf("autorest: ByUnmarshallingJSONWithFallback failed to reject an unregistered type (%v, %#v)", err, v)
+	}
+}
+
+func TestBySkippingOnCondition(t *testing.T) {
+	mi := &mockInspector{}
+	r := mocks.NewResponseWithStatus("204 No Content", http.StatusNoContent)
+	err := Respond(r,
+		mi.ByInspecting(),
+		ByUnmarshallingJSON(&mocks.T{}),
+		BySkippingOnCondition(func(resp *http.Response) bool { return resp.StatusCode == http.StatusNoContent }),
+		ByClosing())
+	if err != nil {
+		t.Errorf("autorest: BySkippingOnCondition returned an unexpected error (%v)", err)
+	}
+	if mi.wasInvoked {
+		t.Error("autorest: BySkippingOnCondition invoked the wrapped Responder despite the condition holding")
+	}
+	if r.Body.(*mocks.Body).IsOpen() {
+		t.Error("autorest: BySkippingOnCondition skipped the decorators following it")
+	}
+}
+
+func TestBySkippingOnConditionInvokesResponderOtherwise(t *testing.T) {
+	mi := &mockInspector{}
+	Respond(mocks.NewResponse(),
+		mi.ByInspecting(),
+		BySkippingOnCondition(func(resp *http.Response) bool { return resp.StatusCode == http.StatusNoContent }),
+		ByClosing())
+	if !mi.wasInvoked {
+		t.Error("autorest: BySkippingOnCondition failed to invoke the wrapped Responder")
 	}
 }
@@ -0,0 +1,150 @@
+package autorest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaValidator is the interface that wraps the Validate method.
+//
+// Validate checks the passed document, decoded from JSON into generic values (i.e., maps, slices,
+// strings, float64s, bools, and nil), against the passed JSON Schema. It returns a description of
+// each violated constraint, if any, or an error if the schema itself is unusable.
+type SchemaValidator interface {
+	Validate(schema []byte, document interface{}) ([]string, error)
+}
+
+// DefaultSchemaValidator is the SchemaValidator used by ByValidatingResponseSchema. It supports a
+// core subset of JSON Schema: the type, enum, properties, required, additionalProperties (as a
+// boolean), items (as a single schema), minimum, maximum, minLength, and maxLength keywords. Users
+// needing full JSON Schema support may replace it or use ByValidatingResponseSchemaWithValidator.
+var DefaultSchemaValidator SchemaValidator = jsonSchemaValidator{}
+
+type jsonSchemaValidator struct{}
+
+// Validate implements the SchemaValidator interface on jsonSchemaValidator.
+func (jsv jsonSchemaValidator) Validate(schema []byte, document interface{}) ([]string, error) {
+	var s map[string]interface{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return nil, NewErrorWithError(err, "autorest", "Validate", UndefinedStatusCode, "Failure decoding JSON Schema")
+	}
+	return validateSchema(s, document, "$"), nil
+}
+
+func validateSchema(s map[string]interface{}, v interface{}, path string) []string {
+	var violations []string
+
+	if t, ok := s["type"]; ok && !matchesSchemaType(t, v) {
+		violations = append(violations, fmt.Sprintf("%s: expected type %v, found %s", path, t, schemaTypeOf(v)))
+		return violations
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			violations = append(violations, fmt.Sprintf("%s: value %v is not one of %v", path, v, enum))
+		}
+	}
+
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		if required, ok := s["required"].([]interface{}); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, ok := tv[name]; !ok {
+						violations = append(violations, fmt.Sprintf("%s: missing required property %s", path, name))
+					}
+				}
+			}
+		}
+		properties, _ := s["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(tv))
+		for k := range tv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := properties[k].(map[string]interface{}); ok {
+				violations = append(violations, validateSchema(ps, tv[k], path+"."+k)...)
+			} else if ap, ok := s["additionalProperties"].(bool); ok && !ap {
+				violations = append(violations, fmt.Sprintf("%s: unexpected property %s", path, k))
+			}
+		}
+
+	case []interface{}:
+		if is, ok := s["items"].(map[string]interface{}); ok {
+			for i, item := range tv {
+				violations = append(violations, validateSchema(is, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case float64:
+		if min, ok := s["minimum"].(float64); ok && tv < min {
+			violations = append(violations, fmt.Sprintf("%s: value %v is less than the minimum %v", path, tv, min))
+		}
+		if max, ok := s["maximum"].(float64); ok && tv > max {
+			violations = append(violations, fmt.Sprintf("%s: value %v is greater than the maximum %v", path, tv, max))
+		}
+
+	case string:
+		n := float64(len([]rune(tv)))
+		if min, ok := s["minLength"].(float64); ok && n < min {
+			violations = append(violations, fmt.Sprintf("%s: length %v is less than the minimum length %v", path, n, min))
+		}
+		if max, ok := s["maxLength"].(float64); ok && n > max {
+			violations = append(violations, fmt.Sprintf("%s: length %v is greater than the maximum length %v", path, n, max))
+		}
+	}
+
+	return violations
+}
+
+// matchesSchemaType returns true if v is of the passed JSON Schema type, which may be a single
+// type name or a list of type names.
+func matchesSchemaType(t interface{}, v interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		vt := schemaTypeOf(v)
+		return tt == vt || (tt == "number" && vt == "integer")
+	case []interface{}:
+		for _, e := range tt {
+			if matchesSchemaType(e, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// schemaTypeOf returns the most specific JSON Schema type name for v.
+func schemaTypeOf(v interface{}) string {
+	switch tv := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if tv == math.Trunc(tv) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return strings.ToLower(reflect.TypeOf(v).Kind().String())
+}
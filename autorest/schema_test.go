@@ -0,0 +1,80 @@
+package autorest
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const (
+	testSchema = `
+    {
+      "type": "object",
+      "required": ["name", "age"],
+      "properties": {
+        "name": {"type": "string", "minLength": 1, "maxLength": 32},
+        "age": {"type": "integer", "minimum": 0, "maximum": 150},
+        "tags": {"type": "array", "items": {"enum": ["a", "b"]}}
+      },
+      "additionalProperties": false
+    }`
+)
+
+func validateTestSchema(t *testing.T, doc string) []string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatalf("autorest: Failed to decode test document (%v)", err)
+	}
+	violations, err := DefaultSchemaValidator.Validate([]byte(testSchema), v)
+	if err != nil {
+		t.Fatalf("autorest: DefaultSchemaValidator#Validate failed (%v)", err)
+	}
+	return violations
+}
+
+func TestDefaultSchemaValidatorAcceptsValidDocument(t *testing.T) {
+	violations := validateTestSchema(t, `{"name": "Rob Pike", "age": 42, "tags": ["a", "b"]}`)
+	if len(violations) != 0 {
+		t.Errorf("autorest: DefaultSchemaValidator rejected a valid document -- received %v", violations)
+	}
+}
+
+func TestDefaultSchemaValidatorReportsAllViolations(t *testing.T) {
+	violations := validateTestSchema(t, `{"name": "", "age": 4.2, "tags": ["c"], "extra": true}`)
+
+	expected := []string{
+		"$.age: expected type integer",
+		"$: unexpected property extra",
+		"$.name: length 0 is less than the minimum length",
+		"$.tags[0]: value c is not one of",
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("autorest: DefaultSchemaValidator reported the wrong violations -- expected %v, received %v", expected, violations)
+	}
+	for i, e := range expected {
+		if !strings.HasPrefix(violations[i], e) {
+			t.Errorf("autorest: DefaultSchemaValidator reported the wrong violation -- expected %q, received %q", e, violations[i])
+		}
+	}
+}
+
+func TestDefaultSchemaValidatorReportsMissingRequiredProperties(t *testing.T) {
+	violations := validateTestSchema(t, `{"name": "Rob Pike"}`)
+	if len(violations) != 1 || !strings.Contains(violations[0], "missing required property age") {
+		t.Errorf("autorest: DefaultSchemaValidator failed to report a missing property -- received %v", violations)
+	}
+}
+
+func TestDefaultSchemaValidatorReportsRangeViolations(t *testing.T) {
+	violations := validateTestSchema(t, `{"name": "Rob Pike", "age": 151}`)
+	if len(violations) != 1 || !strings.Contains(violations[0], "greater than the maximum") {
+		t.Errorf("autorest: DefaultSchemaValidator failed to report a range violation -- received %v", violations)
+	}
+}
+
+func TestDefaultSchemaValidatorReturnsErrorForInvalidSchema(t *testing.T) {
+	_, err := DefaultSchemaValidator.Validate([]byte(`{`), nil)
+	if err == nil {
+		t.Errorf("autorest: DefaultSchemaValidator failed to return an error for an invalid schema")
+	}
+}
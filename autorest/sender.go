@@ -1,9 +1,12 @@
 package autorest
 
 import (
+	"crypto/tls"
 	"log"
 	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -32,6 +35,47 @@ func CreateSender(decorators ...SendDecorator) Sender {
 	return DecorateSender(&http.Client{}, decorators...)
 }
 
+// SenderOptions configures the http.Client created by CreateSenderWithOptions. Zero values leave
+// the corresponding setting of http.DefaultTransport (or, for Timeout, of http.Client) unchanged.
+type SenderOptions struct {
+	// Timeout limits the time taken by each request, including reading the response body.
+	Timeout time.Duration
+
+	// MaxIdleConns limits the number of idle (keep-alive) connections across all hosts.
+	MaxIdleConns int
+
+	// ProxyURL, if set, is the proxy through which all requests are sent.
+	ProxyURL *url.URL
+
+	// TLSConfig, if set, configures TLS connections.
+	TLSConfig *tls.Config
+
+	// DialTimeout limits the time taken to establish connections.
+	DialTimeout time.Duration
+}
+
+// CreateSenderWithOptions creates, decorates, and returns, as a Sender, an http.Client whose
+// transport, a copy of http.DefaultTransport, is configured by the passed SenderOptions.
+func CreateSenderWithOptions(opts SenderOptions, decorators ...SendDecorator) Sender {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.MaxIdleConns > 0 {
+		t.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.ProxyURL != nil {
+		t.Proxy = http.ProxyURL(opts.ProxyURL)
+	}
+	if opts.TLSConfig != nil {
+		t.TLSClientConfig = opts.TLSConfig
+	}
+	if opts.DialTimeout > 0 {
+		t.DialContext = (&net.Dialer{
+			Timeout:   opts.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	return DecorateSender(&http.Client{Transport: t, Timeout: opts.Timeout}, decorators...)
+}
+
 // DecorateSender accepts a Sender and a, possibly empty, set of SendDecorators, which is applies to
 // the Sender. Decorators are applied in the order received, but their affect upon the request
 // depends on whether they are a pre-decorator (change the http.Request and then pass it along) or a
@@ -59,9 +103,10 @@ func Send(r *http.Request, decorators ...SendDecorator) (*http.Response, error)
 // http.Response and possible error. It also accepts a, possibly empty, set of SendDecorators which
 // it will apply the http.Client before invoking the Do method.
 //
-// SendWithSender will not poll or retry requests.
+// SendWithSender will not poll or retry requests. Before invoking any decorator, it records the
+// time the request is sent in the request context (see WithStartTime).
 func SendWithSender(s Sender, r *http.Request, decorators ...SendDecorator) (*http.Response, error) {
-	return DecorateSender(s, decorators...).Do(r)
+	return DecorateSender(s, append(decorators, WithStartTime())...).Do(r)
 }
 
 // AfterDelay returns a SendDecorator that delays for the passed time.Duration before
@@ -104,6 +149,24 @@ func AsIs() SendDecorator {
 	}
 }
 
+// WithStartTime returns a SendDecorator that, unless one is already present, records the current
+// time, as given by DefaultClock, as the start time in the context of the http.Request (see
+// ContextWithStartTime) before invoking the passed Sender. Since the http.Response refers to the
+// http.Request that produced it, RespondDecorators such as ByMeasuringLatency and ByLoggingResponse
+// then measure the time since the request was sent.
+func WithStartTime() SendDecorator {
+	return func(s Sender) Sender {
+		return SenderFunc(func(r *http.Request) (*http.Response, error) {
+			if r != nil {
+				if StartTimeFromContext(r.Context()).IsZero() {
+					r = r.WithContext(ContextWithStartTime(r.Context(), DefaultClock.Now()))
+				}
+			}
+			return s.Do(r)
+		})
+	}
+}
+
 // WithLogging returns a SendDecorator that implements simple before and after logging of the
 // request.
 func WithLogging(logger *log.Logger) SendDecorator {
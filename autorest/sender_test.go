@@ -1,9 +1,11 @@
 package autorest
 
 import (
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"reflect"
 	"testing"
@@ -502,3 +504,114 @@ func TestDelayForBackoff(t *testing.T) {
 // 		t.Error("autorest: DelayForBackoff delayed too long (exceeded 5 times the specified duration)")
 // 	}
 // }
+
+func TestCreateSenderWithOptions(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy.microsoft.com:8080")
+	tc := &tls.Config{ServerName: "microsoft.com"}
+	s := CreateSenderWithOptions(SenderOptions{
+		Timeout:      5 * time.Second,
+		MaxIdleConns: 7,
+		ProxyURL:     proxy,
+		TLSConfig:    tc,
+		DialTimeout:  time.Second,
+	})
+
+	c, ok := s.(*http.Client)
+	if !ok {
+		t.Fatalf("autorest: CreateSenderWithOptions failed to return an http.Client -- returned %T", s)
+	}
+	tr := c.Transport.(*http.Transport)
+	if c.Timeout != 5*time.Second || tr.MaxIdleConns != 7 || tr.TLSClientConfig != tc || tr.DialContext == nil {
+		t.Errorf("autorest: CreateSenderWithOptions failed to apply the options -- %+v", tr)
+	}
+	if u, _ := tr.Proxy(mocks.NewRequest()); u != proxy {
+		t.Errorf("autorest: CreateSenderWithOptions failed to set the proxy -- received %v", u)
+	}
+	if tr == http.DefaultTransport {
+		t.Error("autorest: CreateSenderWithOptions modified http.DefaultTransport")
+	}
+}
+
+func TestCreateSenderWithOptionsLeavesDefaults(t *testing.T) {
+	s := CreateSenderWithOptions(SenderOptions{})
+	tr := s.(*http.Client).Transport.(*http.Transport)
+	if tr.MaxIdleConns != http.DefaultTransport.(*http.Transport).MaxIdleConns {
+		t.Errorf("autorest: CreateSenderWithOptions changed MaxIdleConns to %d", tr.MaxIdleConns)
+	}
+}
+
+func TestCreateSenderWithOptionsAppliesDecorators(t *testing.T) {
+	var msg string
+	s := CreateSenderWithOptions(SenderOptions{},
+		(func() SendDecorator {
+			return func(s Sender) Sender {
+				return SenderFunc(func(r *http.Request) (*http.Response, error) {
+					msg = "decorated"
+					return mocks.NewResponse(), nil
+				})
+			}
+		})())
+	s.Do(mocks.NewRequest())
+	if msg != "decorated" {
+		t.Error("autorest: CreateSenderWithOptions failed to apply the decorators")
+	}
+}
+
+func TestWithStartTime(t *testing.T) {
+	tc := useTestClock(t)
+	var start time.Time
+	s := SenderFunc(func(r *http.Request) (*http.Response, error) {
+		start = StartTimeFromContext(r.Context())
+		return mocks.NewResponse(), nil
+	})
+
+	DecorateSender(s, WithStartTime()).Do(mocks.NewRequest())
+	if !start.Equal(tc.Now()) {
+		t.Errorf("autorest: WithStartTime failed to record the start time -- received %v", start)
+	}
+}
+
+func TestWithStartTimeKeepsExistingStartTime(t *testing.T) {
+	useTestClock(t)
+	earlier := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var start time.Time
+	s := SenderFunc(func(r *http.Request) (*http.Response, error) {
+		start = StartTimeFromContext(r.Context())
+		return mocks.NewResponse(), nil
+	})
+
+	r := mocks.NewRequest()
+	r = r.WithContext(ContextWithStartTime(r.Context(), earlier))
+	DecorateSender(s, WithStartTime()).Do(r)
+	if !start.Equal(earlier) {
+		t.Errorf("autorest: WithStartTime replaced an existing start time -- received %v", start)
+	}
+}
+
+func TestSendWithSenderEnablesLatencyMeasurement(t *testing.T) {
+	tc := useTestClock(t)
+	s := SenderFunc(func(r *http.Request) (*http.Response, error) {
+		tc.Advance(2 * time.Second)
+		resp := mocks.NewResponse()
+		resp.Request = r
+		return resp, nil
+	})
+
+	req, err := Prepare(&http.Request{}, WithBaseURL(mocks.TestURL))
+	if err != nil {
+		t.Fatalf("autorest: Prepare failed (%v)", err)
+	}
+	resp, err := SendWithSender(s, req)
+	if err != nil {
+		t.Fatalf("autorest: SendWithSender failed (%v)", err)
+	}
+	var latency time.Duration
+	l := &testLogger{}
+	Respond(resp,
+		ByMeasuringLatency(&latency),
+		ByLoggingResponse(l),
+		ByClosing())
+	if latency != 2*time.Second || l.value("elapsed") != 2*time.Second {
+		t.Errorf("autorest: Latency failed to include the time since the request was sent -- measured %v, logged %v", latency, l.value("elapsed"))
+	}
+}
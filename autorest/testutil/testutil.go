@@ -0,0 +1,73 @@
+/*
+Package testutil provides helpers for testing code built on the autorest package.
+*/
+package testutil
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// ReplayResponder is an autorest.Responder that replays a fixed sequence of recorded
+// http.Responses.
+type ReplayResponder struct {
+	mu        sync.Mutex
+	responses []*http.Response
+	calls     int
+}
+
+// NewReplayResponder returns a ReplayResponder that replays the passed http.Responses, in order,
+// on successive calls to Respond.
+func NewReplayResponder(responses ...*http.Response) *ReplayResponder {
+	return &ReplayResponder{responses: responses}
+}
+
+// Respond replaces the passed http.Response with the next recorded http.Response. It returns an
+// error, leaving the passed http.Response unchanged, once all recorded responses have been
+// replayed.
+func (rr *ReplayResponder) Respond(resp *http.Response) error {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.calls++
+	if rr.calls > len(rr.responses) {
+		return autorest.NewError("testutil", "Respond", "Call %d exceeds the %d recorded responses",
+			rr.calls, len(rr.responses))
+	}
+	if resp == nil {
+		return autorest.NewError("testutil", "Respond", "Invoked with a nil http.Response")
+	}
+	*resp = *rr.responses[rr.calls-1]
+	return nil
+}
+
+// Calls returns the number of times Respond was called.
+func (rr *ReplayResponder) Calls() int {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.calls
+}
+
+// Remaining returns the number of recorded responses not yet replayed.
+func (rr *ReplayResponder) Remaining() int {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if rr.calls >= len(rr.responses) {
+		return 0
+	}
+	return len(rr.responses) - rr.calls
+}
+
+// ByRespondingWithMock returns an autorest.RespondDecorator that passes mock, in place of the
+// http.Response it receives, to the decorated Responder. Decorators listed before it thus process
+// mock, allowing them to be tested in isolation from any Sender; decorators listed after it, and the
+// caller, continue to see the original http.Response.
+func ByRespondingWithMock(mock *http.Response) autorest.RespondDecorator {
+	return func(r autorest.Responder) autorest.Responder {
+		return autorest.ResponderFunc(func(resp *http.Response) error {
+			return r.Respond(mock)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package testutil
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/mocks"
+)
+
+func TestReplayResponderImplementsResponder(t *testing.T) {
+	var _ autorest.Responder = NewReplayResponder()
+}
+
+func TestReplayResponderReplaysInOrder(t *testing.T) {
+	r1 := mocks.NewResponseWithStatus("200 OK", http.StatusOK)
+	r2 := mocks.NewResponseWithStatus("202 Accepted", http.StatusAccepted)
+	rr := NewReplayResponder(r1, r2)
+
+	for _, expected := range []int{http.StatusOK, http.StatusAccepted} {
+		resp := mocks.NewResponse()
+		if err := rr.Respond(resp); err != nil {
+			t.Fatalf("testutil: ReplayResponder#Respond returned an unexpected error (%v)", err)
+		}
+		if resp.StatusCode != expected {
+			t.Errorf("testutil: ReplayResponder#Respond replayed status code %d -- expected %d", resp.StatusCode, expected)
+		}
+	}
+	if rr.Calls() != 2 || rr.Remaining() != 0 {
+		t.Errorf("testutil: ReplayResponder reported %d calls and %d remaining -- expected 2 and 0", rr.Calls(), rr.Remaining())
+	}
+}
+
+func TestReplayResponderReturnsErrorWhenExhausted(t *testing.T) {
+	rr := NewReplayResponder(mocks.NewResponse())
+
+	rr.Respond(mocks.NewResponse())
+	resp := mocks.NewResponseWithStatus("418 I'm a teapot", 418)
+	if err := rr.Respond(resp); err == nil {
+		t.Error("testutil: ReplayResponder#Respond failed to return an error once exhausted")
+	}
+	if resp.StatusCode != 418 {
+		t.Error("testutil: ReplayResponder#Respond modified the response once exhausted")
+	}
+}
+
+func TestReplayResponderWorksWithRespond(t *testing.T) {
+	rr := NewReplayResponder(mocks.NewResponseWithStatus("404 Not Found", http.StatusNotFound))
+
+	resp := mocks.NewResponse()
+	err := autorest.Respond(resp,
+		func(r autorest.Responder) autorest.Responder { return rr },
+		autorest.WithErrorUnlessOK())
+	if err == nil {
+		t.Error("testutil: ReplayResponder failed to replay the recorded response into the Respond chain")
+	}
+}
+
+func TestByRespondingWithMockPassesMockToDecorators(t *testing.T) {
+	mock := mocks.NewResponseWithContent(`{"name":"Rob Pike","age":42}`)
+	v := &mocks.T{}
+
+	err := autorest.Respond(mocks.NewResponse(),
+		autorest.ByUnmarshallingJSON(v),
+		autorest.ByClosing(),
+		ByRespondingWithMock(mock))
+	if err != nil {
+		t.Fatalf("testutil: ByRespondingWithMock returned an unexpected error (%v)", err)
+	}
+	if v.Name != "Rob Pike" || v.Age != 42 {
+		t.Errorf("testutil: ByRespondingWithMock failed to pass the mock to the decorators -- received %+v", v)
+	}
+	if mock.Body.(*mocks.Body).IsOpen() {
+		t.Error("testutil: ByRespondingWithMock failed to pass the mock to ByClosing")
+	}
+}
+
+func TestByRespondingWithMockLeavesOriginalResponse(t *testing.T) {
+	resp := mocks.NewResponseWithStatus("200 OK", http.StatusOK)
+	var observed int
+
+	err := autorest.Respond(resp,
+		ByRespondingWithMock(mocks.NewResponseWithStatus("404 Not Found", http.StatusNotFound)),
+		autorest.ByObservingStatusCode(func(code int) { observed = code }))
+	if err != nil {
+		t.Fatalf("testutil: ByRespondingWithMock returned an unexpected error (%v)", err)
+	}
+	if observed != http.StatusOK || resp.StatusCode != http.StatusOK {
+		t.Errorf("testutil: ByRespondingWithMock modified the response seen by later decorators -- observed %d", observed)
+	}
+}
@@ -0,0 +1,73 @@
+package autorest
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// xmlElement is an XML element decoded for conversion under the Badgerfish convention.
+type xmlElement struct {
+	name     string
+	value    map[string]interface{}
+	text     strings.Builder
+	children []*xmlElement
+}
+
+// xmlToBadgerfish decodes the XML document read from r into generic values following the
+// Badgerfish convention: Each element becomes an object keyed by the element name, attributes
+// become members prefixed with "@", text content becomes the "$" member, and repeated child
+// elements become arrays. Namespace prefixes are dropped.
+func xmlToBadgerfish(r io.Reader) (map[string]interface{}, error) {
+	d := xml.NewDecoder(r)
+	var stack []*xmlElement
+	var root *xmlElement
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tt := t.(type) {
+		case xml.StartElement:
+			e := &xmlElement{name: tt.Name.Local, value: map[string]interface{}{}}
+			for _, a := range tt.Attr {
+				e.value["@"+a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, e)
+			} else if root == nil {
+				root = e
+			}
+			stack = append(stack, e)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(tt)
+			}
+		case xml.EndElement:
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if text := strings.TrimSpace(e.text.String()); text != "" {
+				e.value["$"] = text
+			}
+			for _, c := range e.children {
+				switch v := e.value[c.name].(type) {
+				case nil:
+					e.value[c.name] = c.value
+				case []interface{}:
+					e.value[c.name] = append(v, c.value)
+				default:
+					e.value[c.name] = []interface{}{v, c.value}
+				}
+			}
+			e.children = nil
+		}
+	}
+	if root == nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return map[string]interface{}{root.name: root.value}, nil
+}
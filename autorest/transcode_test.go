@@ -0,0 +1,51 @@
+package autorest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestXMLToBadgerfish(t *testing.T) {
+	v, err := xmlToBadgerfish(strings.NewReader(xmlT))
+	if err != nil {
+		t.Fatalf("autorest: xmlToBadgerfish failed (%v)", err)
+	}
+	expected := map[string]interface{}{
+		"Person": map[string]interface{}{
+			"Name": map[string]interface{}{"$": "Rob Pike"},
+			"Age":  map[string]interface{}{"$": "42"},
+		},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("autorest: xmlToBadgerfish returned %v -- expected %v", v, expected)
+	}
+}
+
+func TestXMLToBadgerfishConvertsAttributesAndRepeatedElements(t *testing.T) {
+	v, err := xmlToBadgerfish(strings.NewReader(`<Disks count="2"><Disk lun="0">os</Disk><Disk lun="1">data</Disk></Disks>`))
+	if err != nil {
+		t.Fatalf("autorest: xmlToBadgerfish failed (%v)", err)
+	}
+	expected := map[string]interface{}{
+		"Disks": map[string]interface{}{
+			"@count": "2",
+			"Disk": []interface{}{
+				map[string]interface{}{"@lun": "0", "$": "os"},
+				map[string]interface{}{"@lun": "1", "$": "data"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("autorest: xmlToBadgerfish returned %v -- expected %v", v, expected)
+	}
+}
+
+func TestXMLToBadgerfishReturnsErrorForInvalidXML(t *testing.T) {
+	if _, err := xmlToBadgerfish(strings.NewReader(`<Person><Name>`)); err == nil {
+		t.Error("autorest: xmlToBadgerfish failed to return an error for truncated XML")
+	}
+	if _, err := xmlToBadgerfish(strings.NewReader(``)); err == nil {
+		t.Error("autorest: xmlToBadgerfish failed to return an error for an empty document")
+	}
+}
@@ -1,10 +1,186 @@
 package autorest
 
 import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// readCloser combines a Reader with the Closer of the body it replaces.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// peekBody reads up to n bytes from the body of the passed http.Response and then restores the body
+// so that subsequent readers see the complete, unaltered content.
+func peekBody(resp *http.Response, n int64) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, n))
+	resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(b), resp.Body), Closer: resp.Body}
+	return b, err
+}
+
+// onceCloser is an io.ReadCloser that closes the wrapped io.ReadCloser only on the first call to
+// Close. Subsequent calls return the error, if any, of the first.
+type onceCloser struct {
+	io.ReadCloser
+	once sync.Once
+	err  error
+}
+
+func (oc *onceCloser) Close() error {
+	oc.once.Do(func() {
+		oc.err = oc.ReadCloser.Close()
+	})
+	return oc.err
+}
+
+// eofBody is an io.ReadCloser that records whether reading the wrapped io.Reader reached io.EOF.
+type eofBody struct {
+	io.Reader
+	io.Closer
+	eof bool
+}
+
+func (eb *eofBody) Read(p []byte) (int, error) {
+	n, err := eb.Reader.Read(p)
+	if err == io.EOF {
+		eb.eof = true
+	}
+	return n, err
+}
+
+// countingBody is an io.ReadCloser that adds the number of bytes read from the wrapped io.Reader
+// to the int64 pointed to by count.
+type countingBody struct {
+	io.Reader
+	io.Closer
+	count *int64
+}
+
+func (cb *countingBody) Read(p []byte) (int, error) {
+	n, err := cb.Reader.Read(p)
+	atomic.AddInt64(cb.count, int64(n))
+	return n, err
+}
+
+// contextReader is an io.Reader that returns the error of the passed context.Context, rather than
+// reading, once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// timeoutBody is an io.ReadCloser whose reads of the wrapped io.ReadCloser fail with
+// ErrResponseReadTimeout if they take longer than timeout. Since a timed out read may yet complete,
+// each read fills a buffer owned by the reading goroutine rather than the caller's slice.
+type timeoutBody struct {
+	io.ReadCloser
+	timeout time.Duration
+	buf     []byte
+	err     error
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+func (tb *timeoutBody) Read(p []byte) (int, error) {
+	if tb.err != nil {
+		return 0, tb.err
+	}
+	if cap(tb.buf) < len(p) {
+		tb.buf = make([]byte, len(p))
+	}
+	buf := tb.buf[:len(p)]
+	c := make(chan readResult, 1)
+	go func() {
+		n, err := tb.ReadCloser.Read(buf)
+		c <- readResult{n: n, err: err}
+	}()
+
+	t := time.NewTimer(tb.timeout)
+	defer t.Stop()
+	select {
+	case res := <-c:
+		return copy(p, buf[:res.n]), res.err
+	case <-t.C:
+		tb.err = ErrResponseReadTimeout
+		tb.buf = nil
+		tb.ReadCloser.Close()
+		return 0, tb.err
+	}
+}
+
+// prefixBuffer is an io.Writer that retains at most the first max bytes written to it. Writes
+// always succeed.
+type prefixBuffer struct {
+	max int
+	b   []byte
+}
+
+func (pb *prefixBuffer) Write(p []byte) (int, error) {
+	if n := pb.max - len(pb.b); n > 0 {
+		if len(p) < n {
+			n = len(p)
+		}
+		pb.b = append(pb.b, p[:n]...)
+	}
+	return len(p), nil
+}
+
+// limitedBody is an io.ReadCloser that permits reading at most remaining bytes of the wrapped
+// io.ReadCloser. Once the limit is reached, it reads one additional byte to determine whether the
+// content exceeds the limit, in which case it returns ErrResponseBodyTooLarge.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	if lb.exceeded {
+		return 0, ErrResponseBodyTooLarge
+	}
+	if lb.remaining <= 0 {
+		var b [1]byte
+		n, err := lb.ReadCloser.Read(b[:])
+		if n > 0 {
+			lb.exceeded = true
+			return 0, ErrResponseBodyTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > lb.remaining {
+		p = p[:lb.remaining]
+	}
+	n, err := lb.ReadCloser.Read(p)
+	lb.remaining -= int64(n)
+	return n, err
+}
+
 func containsInt(ints []int, n int) bool {
 	for _, i := range ints {
 		if i == n {
@@ -14,6 +190,15 @@ func containsInt(ints []int, n int) bool {
 	return false
 }
 
+func containsString(strs []string, s string) bool {
+	for _, i := range strs {
+		if i == s {
+			return true
+		}
+	}
+	return false
+}
+
 func escapeValueStrings(m map[string]string) map[string]string {
 	for key, value := range m {
 		m[key] = url.QueryEscape(value)
@@ -40,3 +225,52 @@ func ensureValueString(value interface{}) string {
 		return fmt.Sprintf("%v", v)
 	}
 }
+
+// newUUID returns a random (i.e., version 4) UUID in its canonical, lower-case form.
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
+
+// newContentHash returns a new hash.Hash implementing the named algorithm (one of HashMD5,
+// HashSHA256, or HashCRC64, without regard to case) or nil if the algorithm is not supported.
+func newContentHash(algorithm string) hash.Hash {
+	switch strings.ToUpper(algorithm) {
+	case HashMD5:
+		return md5.New()
+	case HashSHA256:
+		return sha256.New()
+	case HashCRC64:
+		return crc64.New(crc64.MakeTable(CRC64Polynomial))
+	}
+	return nil
+}
+
+// contentHashSum returns the digest of the passed hash.Hash, created by newContentHash, in the byte
+// order Azure Storage uses (i.e., little-endian for CRC-64).
+func contentHashSum(h hash.Hash) []byte {
+	digest := h.Sum(nil)
+	if h64, ok := h.(hash.Hash64); ok {
+		binary.LittleEndian.PutUint64(digest, h64.Sum64())
+	}
+	return digest
+}
+
+// contentHashHeader returns the name of the header carrying a request or response body digest of the
+// named algorithm (see newContentHash) or the empty string if the algorithm is not supported.
+func contentHashHeader(algorithm string) string {
+	switch strings.ToUpper(algorithm) {
+	case HashMD5:
+		return headerContentMD5
+	case HashSHA256:
+		return headerContentSHA256
+	case HashCRC64:
+		return headerContentCRC64
+	}
+	return ""
+}
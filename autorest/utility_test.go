@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"regexp"
 	"testing"
 
 	"github.com/Azure/go-autorest/autorest/mocks"
@@ -17,6 +18,11 @@ const (
       "name":"Rob Pike",
       "age":42
     }`
+	xmlT = `<?xml version="1.0" encoding="UTF-8"?>
+	<Person>
+		<Name>Rob Pike</Name>
+		<Age>42</Age>
+	</Person>`
 )
 
 func TestContainsIntFindsValue(t *testing.T) {
@@ -35,6 +41,36 @@ func TestContainsIntDoesNotFindValue(t *testing.T) {
 	}
 }
 
+func TestContainsStringFindsValue(t *testing.T) {
+	strs := []string{"a", "b", "c"}
+	v := "b"
+	if !containsString(strs, v) {
+		t.Errorf("autorest: containsString failed to find %v in %v", v, strs)
+	}
+}
+
+func TestContainsStringDoesNotFindValue(t *testing.T) {
+	strs := []string{"a", "b", "c"}
+	v := "d"
+	if containsString(strs, v) {
+		t.Errorf("autorest: containsString unexpectedly found %v in %v", v, strs)
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	u1, err := newUUID()
+	if err != nil {
+		t.Fatalf("autorest: newUUID failed (%v)", err)
+	}
+	u2, _ := newUUID()
+	if matched, _ := regexp.MatchString(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, u1); !matched {
+		t.Errorf("autorest: newUUID returned %v, which is not a version 4 UUID", u1)
+	}
+	if u1 == u2 {
+		t.Errorf("autorest: newUUID returned %v twice", u1)
+	}
+}
+
 func TestEscapeStrings(t *testing.T) {
 	m := map[string]string{
 		"string": "a long string with = odd characters",